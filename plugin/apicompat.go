@@ -0,0 +1,332 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxAPIChanges bounds the API changes listed in the prompt
+const maxAPIChanges = 50
+
+// Kinds of exported Go symbol
+const (
+	apiFunc   = "func"
+	apiMethod = "method"
+	apiType   = "type"
+	apiField  = "field"
+)
+
+// Ways an exported symbol can break its callers
+const (
+	apiRemoved = "removed"
+	apiChanged = "changed"
+)
+
+// APIChange is an exported symbol removed or changed between the two commits
+type APIChange struct {
+	Package string `json:"package"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Change  string `json:"change"`
+	Old     string `json:"old"`
+	New     string `json:"new,omitempty"`
+}
+
+// Describe formats the change for the prompt
+func (c APIChange) Describe() string {
+	if c.Change == apiRemoved {
+		return fmt.Sprintf("removed %s %s: %s", c.Kind, c.Name, c.Old)
+	}
+	return fmt.Sprintf("changed %s %s: %s -> %s", c.Kind, c.Name, c.Old, c.New)
+}
+
+// APIReview is the potential breaking changes section of the prompt
+type APIReview struct {
+	Changes []APIChange `json:"changes"`
+	Omitted int         `json:"omitted,omitempty"`
+}
+
+// apiKey identifies an exported symbol within a package
+type apiKey struct {
+	kind string
+	name string
+}
+
+// AnalyzeAPICompat parses every package with changed non-test Go files at base and
+// head and returns the exported symbols that were removed or whose signature
+// changed, or nil when there are none. Commands and internal packages are skipped
+// since other modules cannot import them.
+func AnalyzeAPICompat(dir, base, head string, files []ChangedFile) *APIReview {
+	var packages []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		for _, p := range []string{f.Path, f.OldPath} {
+			if !isGoSource(p) {
+				continue
+			}
+			pkg := path.Dir(p)
+			if !seen[pkg] && !isInternalPackage(pkg) {
+				seen[pkg] = true
+				packages = append(packages, pkg)
+			}
+		}
+	}
+	sort.Strings(packages)
+
+	review := &APIReview{}
+	for _, pkg := range packages {
+		before, ok := goPackageAPI(dir, base, pkg)
+		if !ok {
+			continue
+		}
+		after, _ := goPackageAPI(dir, head, pkg)
+		review.Changes = append(review.Changes, diffGoAPI(pkg, before, after)...)
+	}
+	if len(review.Changes) == 0 {
+		return nil
+	}
+	if len(review.Changes) > maxAPIChanges {
+		review.Omitted = len(review.Changes) - maxAPIChanges
+		review.Changes = review.Changes[:maxAPIChanges]
+	}
+	return review
+}
+
+// isGoSource reports whether p is a non-test Go file outside vendor and testdata
+func isGoSource(p string) bool {
+	if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+		return false
+	}
+	for _, segment := range strings.Split(path.Dir(p), "/") {
+		if segment == "vendor" || segment == "testdata" {
+			return false
+		}
+	}
+	return true
+}
+
+// isInternalPackage reports whether a package directory is only importable from within its module
+func isInternalPackage(pkg string) bool {
+	for _, segment := range strings.Split(pkg, "/") {
+		if segment == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// goPackageAPI parses the non-test Go files of a package directory at sha. It
+// returns false when the package does not exist there or is a command.
+func goPackageAPI(dir, sha, pkg string) (map[apiKey]string, bool) {
+	args := []string{"ls-tree", "--name-only", "-z", sha}
+	if pkg != "." {
+		args = append(args, "--", pkg+"/")
+	}
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, false
+	}
+
+	var sources []string
+	for _, name := range splitNul(out) {
+		if isGoSource(name) {
+			sources = append(sources, fileAt(dir, sha, name))
+		}
+	}
+	return parseGoAPI(sources)
+}
+
+// parseGoAPI extracts the exported symbols of a package from the content of its
+// files, mapping each symbol to its signature. Files that do not parse are skipped.
+func parseGoAPI(sources []string) (map[apiKey]string, bool) {
+	fset := token.NewFileSet()
+	api := make(map[apiKey]string)
+	found := false
+	for _, src := range sources {
+		file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		if file.Name.Name == "main" {
+			return nil, false
+		}
+		found = true
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				addFuncAPI(api, fset, decl)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.IsExported() {
+						addTypeAPI(api, fset, spec)
+					}
+				}
+			}
+		}
+	}
+	return api, found
+}
+
+// addFuncAPI records an exported function, or an exported method of an exported type
+func addFuncAPI(api map[apiKey]string, fset *token.FileSet, decl *ast.FuncDecl) {
+	if !decl.Name.IsExported() {
+		return
+	}
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		api[apiKey{apiFunc, decl.Name.Name}] = "func " + decl.Name.Name + funcSignature(fset, decl.Type)
+		return
+	}
+
+	recv := decl.Recv.List[0].Type
+	pointer := ""
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv, pointer = star.X, "*"
+	}
+	typeName := receiverTypeName(recv)
+	if !ast.IsExported(typeName) {
+		return
+	}
+	api[apiKey{apiMethod, typeName + "." + decl.Name.Name}] = fmt.Sprintf("func (%s%s) %s%s", pointer, typeName, decl.Name.Name, funcSignature(fset, decl.Type))
+}
+
+// receiverTypeName strips the type parameters from a receiver type
+func receiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(expr.X)
+	}
+	return ""
+}
+
+// addTypeAPI records an exported type and the exported fields of a struct type
+func addTypeAPI(api map[apiKey]string, fset *token.FileSet, spec *ast.TypeSpec) {
+	name := spec.Name.Name
+	signature := "type " + name
+	if spec.TypeParams != nil {
+		signature += "[" + fieldTypes(fset, spec.TypeParams, true) + "]"
+	}
+	if spec.Assign.IsValid() {
+		signature += " ="
+	}
+
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		api[apiKey{apiType, name}] = signature + " " + formatNode(fset, spec.Type)
+		return
+	}
+	api[apiKey{apiType, name}] = signature + " struct"
+	for _, field := range st.Fields.List {
+		fieldType := formatNode(fset, field.Type)
+		if len(field.Names) == 0 {
+			// An embedded field is named after its type
+			embedded := field.Type
+			if star, ok := embedded.(*ast.StarExpr); ok {
+				embedded = star.X
+			}
+			if sel, ok := embedded.(*ast.SelectorExpr); ok {
+				embedded = sel.Sel
+			}
+			if fieldName := receiverTypeName(embedded); ast.IsExported(fieldName) {
+				api[apiKey{apiField, name + "." + fieldName}] = "embedded " + fieldType
+			}
+			continue
+		}
+		for _, n := range field.Names {
+			if n.IsExported() {
+				api[apiKey{apiField, name + "." + n.Name}] = fieldType
+			}
+		}
+	}
+}
+
+// funcSignature formats the type parameters, parameter types and result types
+// of a function, leaving out parameter names since renaming them is compatible
+func funcSignature(fset *token.FileSet, ft *ast.FuncType) string {
+	var b strings.Builder
+	if ft.TypeParams != nil {
+		b.WriteString("[" + fieldTypes(fset, ft.TypeParams, true) + "]")
+	}
+	b.WriteString("(" + fieldTypes(fset, ft.Params, false) + ")")
+	if ft.Results != nil && len(ft.Results.List) > 0 {
+		results := fieldTypes(fset, ft.Results, false)
+		if len(ft.Results.List) == 1 && len(ft.Results.List[0].Names) <= 1 {
+			b.WriteString(" " + results)
+		} else {
+			b.WriteString(" (" + results + ")")
+		}
+	}
+	return b.String()
+}
+
+// fieldTypes lists the type of every entry of a field list, repeated once per
+// name. Type parameters keep their names since constraints refer to them.
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList, keepNames bool) string {
+	if fields == nil {
+		return ""
+	}
+	var parts []string
+	for _, field := range fields.List {
+		fieldType := formatNode(fset, field.Type)
+		if len(field.Names) == 0 {
+			parts = append(parts, fieldType)
+			continue
+		}
+		for _, n := range field.Names {
+			if keepNames {
+				parts = append(parts, n.Name+" "+fieldType)
+			} else {
+				parts = append(parts, fieldType)
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatNode prints a syntax node on a single line
+func formatNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// diffGoAPI returns the symbols of before that are missing from after or whose signature changed
+func diffGoAPI(pkg string, before, after map[apiKey]string) []APIChange {
+	var changes []APIChange
+	for key, old := range before {
+		// Removing a type already reports its fields and methods as gone
+		if key.kind == apiField || key.kind == apiMethod {
+			typeName, _, _ := strings.Cut(key.name, ".")
+			if _, ok := after[apiKey{apiType, typeName}]; !ok {
+				continue
+			}
+		}
+		now, ok := after[key]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Package: pkg, Kind: key.kind, Name: key.name, Change: apiRemoved, Old: old})
+		case now != old:
+			changes = append(changes, APIChange{Package: pkg, Kind: key.kind, Name: key.name, Change: apiChanged, Old: old, New: now})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes
+}
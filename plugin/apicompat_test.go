@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoAPI(t *testing.T) {
+	src := `package client
+
+import "context"
+
+// Client talks to the server
+type Client struct {
+	BaseURL string
+	Retries, timeout int
+	*Options
+	http.Client
+}
+
+type Options struct{}
+
+type Set[K comparable] map[K]struct{}
+
+type ID = string
+
+type Doer interface {
+	Do(ctx context.Context) error
+}
+
+func New(url string, opts ...Option) (*Client, error) { return nil, nil }
+
+func (c *Client) Do(ctx context.Context, req *Request) (resp *Response, err error) { return nil, nil }
+
+func (s Set[K]) Has(k K) bool { return false }
+
+func (c *Client) retry() {}
+
+func helper() {}
+`
+	got, ok := parseGoAPI([]string{src, "package client\n\nfunc broken( {"})
+	if !ok {
+		t.Fatal("parseGoAPI() did not find a package")
+	}
+	want := map[apiKey]string{
+		{apiType, "Client"}:          "type Client struct",
+		{apiField, "Client.BaseURL"}: "string",
+		{apiField, "Client.Retries"}: "int",
+		{apiField, "Client.Options"}: "embedded *Options",
+		{apiField, "Client.Client"}:  "embedded http.Client",
+		{apiType, "Options"}:         "type Options struct",
+		{apiType, "Set"}:             "type Set[K comparable] map[K]struct{}",
+		{apiType, "ID"}:              "type ID = string",
+		{apiType, "Doer"}:            "type Doer interface { Do(ctx context.Context) error }",
+		{apiFunc, "New"}:             "func New(string, ...Option) (*Client, error)",
+		{apiMethod, "Client.Do"}:     "func (*Client) Do(context.Context, *Request) (*Response, error)",
+		{apiMethod, "Set.Has"}:       "func (Set) Has(K) bool",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGoAPI() =\n%v\nwant\n%v", got, want)
+	}
+
+	if _, ok := parseGoAPI([]string{"package main\n\nfunc Run() {}\n"}); ok {
+		t.Error("parseGoAPI() should skip commands")
+	}
+}
+
+func TestDiffGoAPI(t *testing.T) {
+	before := map[apiKey]string{
+		{apiFunc, "New"}:             "func New(string) *Client",
+		{apiFunc, "Keep"}:            "func Keep()",
+		{apiType, "Client"}:          "type Client struct",
+		{apiField, "Client.Timeout"}: "int",
+		{apiMethod, "Client.Close"}:  "func (*Client) Close() error",
+		{apiType, "Gone"}:            "type Gone struct",
+		{apiField, "Gone.Name"}:      "string",
+		{apiMethod, "Gone.Run"}:      "func (Gone) Run()",
+	}
+	after := map[apiKey]string{
+		{apiFunc, "New"}:             "func New(string, ...Option) *Client",
+		{apiFunc, "Keep"}:            "func Keep()",
+		{apiFunc, "Added"}:           "func Added()",
+		{apiType, "Client"}:          "type Client struct",
+		{apiField, "Client.Timeout"}: "time.Duration",
+	}
+
+	want := []APIChange{
+		{Package: "client", Kind: apiMethod, Name: "Client.Close", Change: apiRemoved, Old: "func (*Client) Close() error"},
+		{Package: "client", Kind: apiField, Name: "Client.Timeout", Change: apiChanged, Old: "int", New: "time.Duration"},
+		{Package: "client", Kind: apiType, Name: "Gone", Change: apiRemoved, Old: "type Gone struct"},
+		{Package: "client", Kind: apiFunc, Name: "New", Change: apiChanged, Old: "func New(string) *Client", New: "func New(string, ...Option) *Client"},
+	}
+	if got := diffGoAPI("client", before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffGoAPI() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := want[3].Describe(); got != "changed func New: func New(string) *Client -> func New(string, ...Option) *Client" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+func TestAnalyzeAPICompat(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("client/client.go", "package client\n\nfunc New(url string) *Client { return nil }\n\ntype Client struct{ Timeout int }\n")
+	repo.write("client/util.go", "package client\n\nfunc Helper() {}\n")
+	repo.write("internal/cache/cache.go", "package cache\n\nfunc Get() {}\n")
+	repo.write("cmd/tool/main.go", "package main\n\nfunc Run() {}\n")
+	base := repo.commit("initial")
+
+	repo.write("client/client.go", "package client\n\nfunc New(url string, retries int) *Client { return nil }\n\ntype Client struct{ Timeout int }\n")
+	repo.write("client/client_test.go", "package client\n\nfunc TestOnly() {}\n")
+	repo.write("internal/cache/cache.go", "package cache\n\nfunc Fetch() {}\n")
+	repo.write("cmd/tool/main.go", "package main\n\nfunc Start() {}\n")
+	head := repo.commit("change api")
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+	review := AnalyzeAPICompat(repo.dir, base, head, files)
+	if review == nil {
+		t.Fatal("AnalyzeAPICompat() = nil, want changes")
+	}
+	want := []APIChange{{
+		Package: "client",
+		Kind:    apiFunc,
+		Name:    "New",
+		Change:  apiChanged,
+		Old:     "func New(string) *Client",
+		New:     "func New(string, int) *Client",
+	}}
+	if !reflect.DeepEqual(review.Changes, want) {
+		t.Errorf("Changes = %+v, want %+v", review.Changes, want)
+	}
+}
@@ -0,0 +1,286 @@
+package plugin
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bounds of the caller section of the prompt
+const (
+	maxCallerFunctions = 20
+	maxCallSites       = 10
+)
+
+// CallSite is a call of a changed function
+type CallSite struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Caller  string `json:"caller"`
+	Snippet string `json:"snippet"`
+}
+
+// ChangedFunction is a Go function or method whose body or signature changed,
+// with the call sites found outside the changed lines
+type ChangedFunction struct {
+	Name    string     `json:"name"`
+	Path    string     `json:"path"`
+	Line    int        `json:"line"`
+	Callers []CallSite `json:"callers"`
+	Omitted int        `json:"omitted,omitempty"`
+
+	pkgDir  string
+	pkgName string
+	method  bool
+	ident   string
+}
+
+// CallerReview is the caller section of the prompt
+type CallerReview struct {
+	Functions []ChangedFunction `json:"functions"`
+	Omitted   int               `json:"omitted,omitempty"`
+}
+
+// FindCallers lists the call sites across the module at sha of the Go functions
+// whose existing lines changed, or returns nil when none of them has callers.
+// Calls are matched by name within the package and through imports of it, so
+// method calls on values of other types with the same method name may be listed.
+func FindCallers(dir, sha string, files []ChangedFile, diffs []FileDiff) *CallerReview {
+	added := make(map[string]map[int]bool)
+	for _, d := range diffs {
+		lines := make(map[int]bool)
+		for _, l := range d.AddedLines() {
+			lines[l] = true
+		}
+		added[d.Path] = lines
+	}
+
+	changed := changedGoFunctions(dir, sha, files, diffs, added)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	modulePath := goModulePath(dir, sha)
+
+	for _, candidate := range callerCandidates(dir, sha, changed) {
+		content := fileAt(dir, sha, candidate)
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, candidate, content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(content, "\n")
+		imports := fileImports(file)
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			caller := funcDeclName(fn)
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				line := fset.Position(call.Pos()).Line
+				if added[candidate][line] {
+					return true
+				}
+				for i := range changed {
+					f := &changed[i]
+					if !callsFunction(call, f, candidate, file.Name.Name, imports, modulePath) {
+						continue
+					}
+					if candidate == f.Path && caller == f.Name {
+						continue
+					}
+					if len(f.Callers) >= maxCallSites {
+						f.Omitted++
+						continue
+					}
+					f.Callers = append(f.Callers, CallSite{
+						Path:    candidate,
+						Line:    line,
+						Caller:  caller,
+						Snippet: strings.TrimSpace(lines[line-1]),
+					})
+				}
+				return true
+			})
+		}
+	}
+
+	review := &CallerReview{}
+	for _, f := range changed {
+		if len(f.Callers) > 0 {
+			review.Functions = append(review.Functions, f)
+		}
+	}
+	if len(review.Functions) == 0 {
+		return nil
+	}
+	if len(review.Functions) > maxCallerFunctions {
+		review.Omitted = len(review.Functions) - maxCallerFunctions
+		review.Functions = review.Functions[:maxCallerFunctions]
+	}
+	return review
+}
+
+// changedGoFunctions returns the functions of the changed Go files at sha that
+// contain changed lines. Functions whose lines were all added have no existing callers.
+func changedGoFunctions(dir, sha string, files []ChangedFile, diffs []FileDiff, added map[string]map[int]bool) []ChangedFunction {
+	hunks := make(map[string][]Hunk)
+	for _, d := range diffs {
+		hunks[d.Path] = d.Hunks
+	}
+
+	var changed []ChangedFunction
+	for _, f := range files {
+		if f.Status == "D" || !isGoSource(f.Path) {
+			continue
+		}
+		var ranges []span
+		for _, h := range hunks[f.Path] {
+			ranges = append(ranges, changedRanges(h)...)
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, f.Path, fileAt(dir, sha, f.Path), parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil && (fn.Name.Name == "init" || fn.Name.Name == "main") {
+				continue
+			}
+			s := span{fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line}
+			if allAdded(s, added[f.Path]) || !overlaps(s, ranges) {
+				continue
+			}
+			changed = append(changed, ChangedFunction{
+				Name:    funcDeclName(fn),
+				Path:    f.Path,
+				Line:    s.start,
+				pkgDir:  path.Dir(f.Path),
+				pkgName: file.Name.Name,
+				method:  fn.Recv != nil,
+				ident:   fn.Name.Name,
+			})
+		}
+	}
+	return changed
+}
+
+// overlaps reports whether s shares a line with any of the ranges
+func overlaps(s span, ranges []span) bool {
+	for _, r := range ranges {
+		if r.start <= s.end && s.start <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// callerCandidates returns the Go files at sha that mention the name of a changed function
+func callerCandidates(dir, sha string, changed []ChangedFunction) []string {
+	args := []string{"grep", "-l", "-w", "-F"}
+	seen := make(map[string]bool)
+	for _, f := range changed {
+		if !seen[f.ident] {
+			seen[f.ident] = true
+			args = append(args, "-e", f.ident)
+		}
+	}
+	args = append(args, sha, "--", "*.go")
+
+	// git grep exits with status 1 when nothing matches
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		p := strings.TrimPrefix(line, sha+":")
+		if p != "" && !strings.HasPrefix(p, "vendor/") && !strings.Contains(p, "/vendor/") {
+			candidates = append(candidates, p)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// fileImports maps the local names of the imports of a file to their paths
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	return imports
+}
+
+// callsFunction reports whether a call in the file at p may call the changed function f
+func callsFunction(call *ast.CallExpr, f *ChangedFunction, p, pkgName string, imports map[string]string, modulePath string) bool {
+	samePackage := path.Dir(p) == f.pkgDir && pkgName == f.pkgName
+	importPath := ""
+	if modulePath != "" {
+		importPath = path.Join(modulePath, f.pkgDir)
+	}
+
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return !f.method && samePackage && fun.Name == f.ident
+	case *ast.SelectorExpr:
+		if fun.Sel.Name != f.ident {
+			return false
+		}
+		x, isIdent := fun.X.(*ast.Ident)
+		qualifier := ""
+		if isIdent {
+			qualifier = imports[x.Name]
+		}
+		if !f.method {
+			return importPath != "" && qualifier == importPath
+		}
+		if qualifier != "" {
+			return false
+		}
+		if path.Dir(p) == f.pkgDir {
+			return true
+		}
+		for _, imported := range imports {
+			if imported == importPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// funcDeclName names a function, or a method as Type.Method
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	return receiverTypeName(recv) + "." + fn.Name.Name
+}
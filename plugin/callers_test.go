@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCallers(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("go.mod", "module example.com/shop\n\ngo 1.24\n")
+	repo.write("cart/cart.go", `package cart
+
+type Cart struct{ items []int }
+
+func Total(prices []int) int {
+	sum := 0
+	for _, p := range prices {
+		sum += p
+	}
+	return sum
+}
+
+func (c *Cart) Add(price int) {
+	c.items = append(c.items, price)
+}
+
+func (c *Cart) Sum() int {
+	return Total(c.items)
+}
+`)
+	repo.write("api/handler.go", `package api
+
+import (
+	"example.com/shop/cart"
+	other "example.com/shop/pricing"
+)
+
+func Checkout(prices []int) int {
+	c := &cart.Cart{}
+	c.Add(1)
+	other.Total(prices)
+	return cart.Total(prices)
+}
+`)
+	repo.write("pricing/pricing.go", "package pricing\n\nfunc Total(prices []int) int { return 0 }\n")
+	repo.write("cmd/main.go", "package main\n\nfunc main() {}\n")
+	base := repo.commit("initial")
+
+	repo.write("cart/cart.go", `package cart
+
+type Cart struct{ items []int }
+
+func Total(prices []int) int {
+	sum := 0
+	for _, p := range prices {
+		sum += p * 2
+	}
+	return sum
+}
+
+func (c *Cart) Add(price int) {
+	c.items = append(c.items, price)
+}
+
+func (c *Cart) Sum() int {
+	return Total(c.items)
+}
+`)
+	repo.write("cmd/main.go", "package main\n\nimport \"example.com/shop/cart\"\n\nfunc main() {\n\tcart.Total(nil)\n}\n")
+	head := repo.commit("double prices")
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+	diffs, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+
+	review := FindCallers(repo.dir, head, files, diffs)
+	if review == nil || len(review.Functions) != 1 {
+		t.Fatalf("FindCallers() = %+v, want one changed function", review)
+	}
+	got := review.Functions[0]
+	if got.Name != "Total" || got.Path != "cart/cart.go" || got.Line != 5 {
+		t.Errorf("function = %s %s:%d, want Total cart/cart.go:5", got.Name, got.Path, got.Line)
+	}
+	want := []CallSite{
+		{Path: "api/handler.go", Line: 12, Caller: "Checkout", Snippet: "return cart.Total(prices)"},
+		{Path: "cart/cart.go", Line: 18, Caller: "Cart.Sum", Snippet: "return Total(c.items)"},
+	}
+	if !reflect.DeepEqual(got.Callers, want) {
+		t.Errorf("Callers = %+v, want %+v", got.Callers, want)
+	}
+}
+
+func TestFindCallersMethods(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("go.mod", "module example.com/shop\n\ngo 1.24\n")
+	repo.write("cart/cart.go", "package cart\n\ntype Cart struct{ n int }\n\nfunc (c *Cart) Add(n int) {\n\tc.n += n\n}\n")
+	repo.write("api/handler.go", "package api\n\nimport \"example.com/shop/cart\"\n\nfunc Buy(c *cart.Cart) {\n\tc.Add(1)\n}\n")
+	repo.write("report/report.go", "package report\n\ntype Totals struct{}\n\nfunc (Totals) Add(n int) {}\n\nfunc Run(t Totals) {\n\tt.Add(1)\n}\n")
+	base := repo.commit("initial")
+
+	repo.write("cart/cart.go", "package cart\n\ntype Cart struct{ n int }\n\nfunc (c *Cart) Add(n int) {\n\tc.n -= n\n}\n")
+	head := repo.commit("subtract")
+
+	files, _ := ListChangedFiles(repo.dir, base, head)
+	diffs, _ := LoadDiff(repo.dir, base, head)
+	review := FindCallers(repo.dir, head, files, diffs)
+	if review == nil || len(review.Functions) != 1 {
+		t.Fatalf("FindCallers() = %+v, want one changed method", review)
+	}
+	want := []CallSite{{Path: "api/handler.go", Line: 6, Caller: "Buy", Snippet: "c.Add(1)"}}
+	if got := review.Functions[0]; got.Name != "Cart.Add" || !reflect.DeepEqual(got.Callers, want) {
+		t.Errorf("function = %s %+v, want Cart.Add %+v", got.Name, got.Callers, want)
+	}
+}
@@ -0,0 +1,464 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// programName is the binary name used in help output
+const programName = "drone-ai-review"
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, settings Settings) error
+}
+
+// cli carries the output streams shared by all commands
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// commands lists the subcommands in help order. Nested commands such as
+// "config print" are registered under their full space-separated name.
+var commands = []command{
+	{name: "generate", summary: "Render the review prompt and manifest (default when no command is given)", run: (*cli).generate},
+	{name: "validate", summary: "Check the resolved settings and that the prompt template renders", run: (*cli).validate},
+	{name: "publish", summary: "Validate the review output and export step output variables", run: (*cli).publish},
+	{name: "summary", summary: "Render the summary in the review output as a Markdown pull request comment", run: (*cli).summary},
+	{name: "report", summary: "Render the review output with the commented code as Markdown and HTML reports", run: (*cli).report},
+	{name: "patch", summary: "Turn the suggestion blocks in the review output into a git patch, or apply them to the working tree", run: (*cli).patch},
+	{name: "render-diff", summary: "Print the annotated OLD/NEW diff the prompt asks the model to read", run: (*cli).renderDiff},
+	{name: "version", summary: "Print the plugin version", run: (*cli).version},
+	{name: "config print", summary: "Print the resolved settings as JSON with secrets redacted", run: (*cli).configPrint},
+}
+
+// Run executes the command line and returns the process exit code. Settings are
+// loaded from the environment first; flags override them.
+func Run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+
+	cmd, rest, err := findCommand(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n\n", err)
+		c.usage(stderr)
+		return 2
+	}
+	if cmd == nil {
+		c.usage(stdout)
+		return 0
+	}
+
+	settings := NewSettings()
+	fs := flag.NewFlagSet(programName+" "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if cmd.name != "version" {
+		BindFlags(fs, &settings)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n", programName, cmd.name, cmd.summary)
+		if cmd.name != "version" {
+			fmt.Fprintf(fs.Output(), "\nEvery flag can also be set through the environment variable shown in its description.\n\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Error: unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return 2
+	}
+
+	// Profiles replace the settings they bundle, so resolve them before any command reads them
+	if cmd.name != "version" {
+		resolved, err := settings.WithProfile(explicitSettings(fs))
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		settings = resolved
+	}
+
+	if err := cmd.run(c, settings); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// findCommand resolves the subcommand named by args and returns the remaining arguments.
+// A nil command means help was requested.
+func findCommand(args []string) (*command, []string, error) {
+	// Drone runs the image without arguments, and flags alone imply generate
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && !isHelpArg(args[0]) {
+		return &commands[0], args, nil
+	}
+	if isHelpArg(args[0]) || args[0] == "help" {
+		return nil, nil, nil
+	}
+
+	// Prefer the longest match so "config print" wins over a hypothetical "config"
+	var match *command
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(words) <= len(args) && strings.Join(args[:len(words)], " ") == commands[i].name {
+			if match == nil || len(words) > len(strings.Fields(match.name)) {
+				match = &commands[i]
+			}
+		}
+	}
+	if match == nil {
+		return nil, nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+	return match, args[len(strings.Fields(match.name)):], nil
+}
+
+// isHelpArg reports whether arg asks for help
+func isHelpArg(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// usage prints the list of commands
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", programName)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", programName)
+}
+
+// generate renders the prompt and manifest
+func (c *cli) generate(settings Settings) error {
+	// Keep stdout clean when it carries the prompt
+	out := c.stdout
+	if settings.UsesStdout() {
+		out = c.stderr
+	}
+
+	printBanner(out, settings)
+	if err := writePrompt(settings, c.stdout, c.stderr); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Plugin execution completed successfully!")
+	return nil
+}
+
+// printBanner displays the resolved configuration
+func printBanner(w io.Writer, settings Settings) {
+	fmt.Fprintln(w, "Drone AI Review Plugin")
+	fmt.Fprintln(w, "======================")
+	fmt.Fprintf(w, "Version: %s\n", Version)
+	fmt.Fprintf(w, "Repository: %s\n", settings.RepoName)
+	fmt.Fprintf(w, "Source Branch: %s\n", settings.SourceBranch)
+	fmt.Fprintf(w, "Target Branch: %s\n", settings.TargetBranch)
+	fmt.Fprintf(w, "Merge Base SHA: %s\n", settings.MergeBaseSha)
+	fmt.Fprintf(w, "Source SHA: %s\n", settings.SourceSha)
+	fmt.Fprintf(w, "Output File: %s\n", settings.OutputFile)
+	fmt.Fprintf(w, "Review Output File: %s\n", settings.ReviewOutputFile)
+	fmt.Fprintf(w, "Manifest File: %s\n", settings.ManifestPath())
+	if settings.Profile != "" {
+		fmt.Fprintf(w, "Profile: %s\n", settings.Profile)
+	}
+	fmt.Fprintf(w, "Comment Count: %d\n", settings.CommentCount)
+	if name, hash := selectedPromptTemplate(settings); hash != "" {
+		fmt.Fprintf(w, "Prompt Template: %s (%s)\n", name, hash)
+	}
+	fmt.Fprintf(w, "Enable Bugs: %v\n", settings.EnableBugs)
+	fmt.Fprintf(w, "Enable Performance: %v\n", settings.EnablePerformance)
+	fmt.Fprintf(w, "Enable Scalability: %v\n", settings.EnableScalability)
+	fmt.Fprintf(w, "Enable Code Smell: %v\n", settings.EnableCodeSmell)
+	fmt.Fprintf(w, "Dry Run: %v\n", settings.DryRun)
+	fmt.Fprintln(w, "======================")
+}
+
+// validate checks the settings and renders the prompt in memory
+func (c *cli) validate(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("invalid settings:\n%w", err)
+	}
+	if _, err := RenderPrompt(settings); err != nil {
+		return err
+	}
+	if settings.MergeBaseSha == "" || settings.SourceSha == "" {
+		fmt.Fprintln(c.stderr, "Warning: merge base or source SHA is not set; the model will produce an empty review")
+	}
+
+	fmt.Fprintln(c.stdout, "Configuration is valid")
+	return nil
+}
+
+// publish validates the review written by the model and exports step output variables
+func (c *cli) publish(settings Settings) error {
+	output, err := ReadReviewOutput(settings.ReviewOutputFile)
+	if err != nil {
+		return err
+	}
+	if err := output.Validate(); err != nil {
+		return fmt.Errorf("invalid review output %s:\n%w", settings.ReviewOutputFile, err)
+	}
+
+	if settings.MergeToolFindings && settings.ToolReports != "" {
+		if err := c.mergeToolFindings(settings, &output); err != nil {
+			return err
+		}
+	}
+	if settings.EnableCommitReview {
+		if err := c.mergeCommitFindings(settings, &output); err != nil {
+			return err
+		}
+	}
+	if settings.VerifySuggestions != verifyOff && settings.VerifySuggestions != "" {
+		if err := c.verifySuggestions(settings, &output); err != nil {
+			return err
+		}
+	}
+
+	counts := output.CountByType()
+	fmt.Fprintf(c.stdout, "Review output %s contains %d comments\n", settings.ReviewOutputFile, len(output.Reviews))
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(c.stdout, "  %s: %d\n", t, counts[t])
+	}
+
+	if settings.OutputVarsFile == "" {
+		return nil
+	}
+	vars := []string{
+		"REVIEW_OUTPUT_FILE=" + settings.ReviewOutputFile,
+		fmt.Sprintf("REVIEW_COMMENT_COUNT=%d", len(output.Reviews)),
+		"PROMPT_FILE=" + settings.OutputFile,
+		"MANIFEST_FILE=" + settings.ManifestPath(),
+	}
+	if err := appendOutputVars(settings.OutputVarsFile, vars); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Exported output variables to: %s\n", settings.OutputVarsFile)
+	return nil
+}
+
+// mergeToolFindings adds the tool findings on changed lines to the review output file
+func (c *cli) mergeToolFindings(settings Settings, output *ReviewOutput) error {
+	diffs, err := LoadDiff(".", settings.MergeBaseSha, settings.SourceSha)
+	if err != nil {
+		return fmt.Errorf("could not merge tool findings: %w", err)
+	}
+	findings, warnings := CollectToolFindings(settings.ToolReports, diffs)
+	for _, warning := range warnings {
+		fmt.Fprintf(c.stderr, "Warning: %s\n", warning)
+	}
+
+	merged := MergeToolFindings(output, findings)
+	if merged == 0 {
+		return nil
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := WriteReviewOutput(settings.ReviewOutputFile, *output, mode); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Merged %d tool findings into %s\n", merged, settings.ReviewOutputFile)
+	return nil
+}
+
+// mergeCommitFindings adds the commit convention violations to the review output file
+func (c *cli) mergeCommitFindings(settings Settings, output *ReviewOutput) error {
+	commits, err := ListCommits(".", settings.MergeBaseSha, settings.SourceSha)
+	if err != nil {
+		return fmt.Errorf("could not merge commit findings: %w", err)
+	}
+
+	merged := MergeCommitFindings(output, CheckCommits(commits, NewCommitConventions(settings)))
+	if merged == 0 {
+		return nil
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := WriteReviewOutput(settings.ReviewOutputFile, *output, mode); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Merged %d commit findings into %s\n", merged, settings.ReviewOutputFile)
+	return nil
+}
+
+// verifySuggestions checks the Go suggestion blocks of the review output and
+// records the results in the review output file
+func (c *cli) verifySuggestions(settings Settings, output *ReviewOutput) error {
+	result := VerifySuggestions(".", settings.SourceSha, output, settings.VerifySuggestions, settings.VerifySuggestionsBuild)
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(c.stderr, "Warning: %s\n", warning)
+	}
+	if result.Checked == 0 {
+		return nil
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := WriteReviewOutput(settings.ReviewOutputFile, *output, mode); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Verified the suggestions of %d comments: %d failed\n", result.Checked, result.Failed)
+	return nil
+}
+
+// appendOutputVars appends KEY=value lines to a CI step output file
+func appendOutputVars(path string, vars []string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output variables file: %w", err)
+	}
+	defer file.Close()
+
+	for _, v := range vars {
+		if _, err := fmt.Fprintln(file, v); err != nil {
+			return fmt.Errorf("failed to write output variables: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// summary renders the review output as a Markdown pull request comment
+func (c *cli) summary(settings Settings) error {
+	output, err := ReadReviewOutput(settings.ReviewOutputFile)
+	if err != nil {
+		return err
+	}
+	if err := output.Validate(); err != nil {
+		return fmt.Errorf("invalid review output %s:\n%w", settings.ReviewOutputFile, err)
+	}
+
+	markdown := RenderSummary(output)
+	if settings.SummaryFile == StdoutPath || settings.SummaryFile == "" {
+		_, err := io.WriteString(c.stdout, markdown)
+		return err
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(settings.SummaryFile, []byte(markdown), mode, !settings.NoOverwrite); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Successfully generated summary file at: %s\n", settings.SummaryFile)
+	return nil
+}
+
+// report renders the review output and the code it comments on as Markdown and HTML
+func (c *cli) report(settings Settings) error {
+	output, err := ReadReviewOutput(settings.ReviewOutputFile)
+	if err != nil {
+		return err
+	}
+	if err := output.Validate(); err != nil {
+		return fmt.Errorf("invalid review output %s:\n%w", settings.ReviewOutputFile, err)
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+
+	report := BuildReport(".", settings.SourceSha, output)
+	if settings.ReportMarkdownFile != "" {
+		if err := writeFileAtomic(settings.ReportMarkdownFile, []byte(RenderReportMarkdown(report)), mode, !settings.NoOverwrite); err != nil {
+			return fmt.Errorf("failed to write Markdown report: %w", err)
+		}
+		fmt.Fprintf(c.stdout, "Successfully generated Markdown report at: %s\n", settings.ReportMarkdownFile)
+	}
+	if settings.ReportHTMLFile != "" {
+		page, err := RenderReportHTML(report)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(settings.ReportHTMLFile, page, mode, !settings.NoOverwrite); err != nil {
+			return fmt.Errorf("failed to write HTML report: %w", err)
+		}
+		fmt.Fprintf(c.stdout, "Successfully generated HTML report at: %s\n", settings.ReportHTMLFile)
+	}
+	return nil
+}
+
+// patch writes the suggestion blocks of the review output as a git patch, or
+// applies them to the working tree
+func (c *cli) patch(settings Settings) error {
+	output, err := ReadReviewOutput(settings.ReviewOutputFile)
+	if err != nil {
+		return err
+	}
+	if err := output.Validate(); err != nil {
+		return fmt.Errorf("invalid review output %s:\n%w", settings.ReviewOutputFile, err)
+	}
+	diffs, err := LoadDiff(".", settings.MergeBaseSha, settings.SourceSha)
+	if err != nil {
+		return fmt.Errorf("could not map suggestions onto the diff: %w", err)
+	}
+
+	patch := PlanSuggestions(".", settings.SourceSha, output, diffs)
+	for _, r := range patch.Rejected {
+		fmt.Fprintf(c.stderr, "Warning: rejected the suggestion of %s: %s\n", r.Suggestion, r.Reason)
+	}
+
+	if settings.ApplySuggestions {
+		if err := patch.Apply("."); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Applied %d suggestions to %d files\n", len(patch.Applied), len(patch.Files))
+		return nil
+	}
+	if settings.SuggestionPatchFile == StdoutPath || settings.SuggestionPatchFile == "" {
+		_, err := io.WriteString(c.stdout, patch.Unified())
+		return err
+	}
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(settings.SuggestionPatchFile, []byte(patch.Unified()), mode, !settings.NoOverwrite); err != nil {
+		return fmt.Errorf("failed to write suggestion patch: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Successfully generated patch with %d suggestions at: %s\n", len(patch.Applied), settings.SuggestionPatchFile)
+	return nil
+}
+
+// renderDiff prints the annotated diff between the merge base and source SHA
+func (c *cli) renderDiff(settings Settings) error {
+	return RenderDiff(c.stdout, ".", settings.MergeBaseSha, settings.SourceSha)
+}
+
+// version prints the plugin version
+func (c *cli) version(_ Settings) error {
+	fmt.Fprintf(c.stdout, "%s %s\n", programName, Version)
+	return nil
+}
+
+// configPrint prints the resolved settings with secrets redacted
+func (c *cli) configPrint(settings Settings) error {
+	data, err := json.MarshalIndent(redactSettings(settings), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "%s\n", data)
+	return nil
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCommands(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantCode     int
+		wantStdout   []string
+		wantStderr   []string
+		unwantStdout []string
+	}{
+		{
+			name:       "help lists commands",
+			args:       []string{"help"},
+			wantCode:   0,
+			wantStdout: []string{"generate", "validate", "publish", "render-diff", "version", "config print"},
+		},
+		{
+			name:       "version",
+			args:       []string{"version"},
+			wantCode:   0,
+			wantStdout: []string{"drone-ai-review " + Version},
+		},
+		{
+			name:       "config print applies flags over environment",
+			args:       []string{"config", "print", "-comment-count", "3", "-enable-bugs=false"},
+			wantCode:   0,
+			wantStdout: []string{`"comment_count": 3`, `"enable_bugs": false`, `"repo_name": "env-repo"`},
+		},
+		{
+			name:       "profile replaces the settings it bundles unless set explicitly",
+			args:       []string{"config", "print", "-profile", "bugs-only", "-comment-count", "3"},
+			wantCode:   0,
+			wantStdout: []string{`"comment_count": 3`, `"enable_bugs": true`, `"enable_performance": false`, `"profile": "bugs-only"`},
+		},
+		{
+			name:       "unknown profile",
+			args:       []string{"validate", "-profile", "nope"},
+			wantCode:   1,
+			wantStderr: []string{`unknown profile "nope"`, "bugs-only, mentor, security-auditor"},
+		},
+		{
+			name:       "validate accepts defaults",
+			args:       []string{"validate"},
+			wantCode:   0,
+			wantStdout: []string{"Configuration is valid"},
+		},
+		{
+			name:       "validate rejects invalid settings",
+			args:       []string{"validate", "-comment-count", "0", "-file-mode", "abc"},
+			wantCode:   1,
+			wantStderr: []string{"comment_count must be at least 1", "invalid file mode"},
+		},
+		{
+			name:       "unknown command",
+			args:       []string{"bogus"},
+			wantCode:   2,
+			wantStderr: []string{`unknown command "bogus"`},
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"validate", "-no-such-flag"},
+			wantCode:   2,
+			wantStderr: []string{"no-such-flag"},
+		},
+		{
+			name:       "command help documents environment variables",
+			args:       []string{"generate", "-h"},
+			wantCode:   0,
+			wantStderr: []string{"-comment-count", "PLUGIN_COMMENT_COUNT", "fallback DRONE_REPO_NAME"},
+		},
+		{
+			name:         "flags alone imply generate",
+			args:         []string{"-dry-run", "-merge-base-sha", "abc123", "-source-sha", "def456"},
+			wantCode:     0,
+			wantStdout:   []string{"Resolved settings:", "abc123...def456"},
+			unwantStdout: []string{"Drone AI Review Plugin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("PLUGIN_REPO_NAME", "env-repo")
+
+			var stdout, stderr strings.Builder
+			code := Run(tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("Run(%v) = %d, want %d\nstderr: %s", tt.args, code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("stdout should contain %q, got:\n%s", want, stdout.String())
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr should contain %q, got:\n%s", want, stderr.String())
+				}
+			}
+			for _, unwanted := range tt.unwantStdout {
+				if strings.Contains(stdout.String(), unwanted) {
+					t.Errorf("stdout should not contain %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRunGenerate(t *testing.T) {
+	os.Clearenv()
+	tempDir := t.TempDir()
+	outputFile := filepath.Join(tempDir, "task.txt")
+
+	var stdout, stderr strings.Builder
+	code := Run([]string{"generate", "-repo-name", "flag-repo", "-output-file", outputFile}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(content), `"flag-repo"`) {
+		t.Error("Prompt should use the repository name passed as a flag")
+	}
+	if !strings.Contains(stdout.String(), "Plugin execution completed successfully!") {
+		t.Error("Generate should report completion on stdout")
+	}
+}
+
+func TestRunPublish(t *testing.T) {
+	os.Clearenv()
+	tempDir := t.TempDir()
+	reviewFile := filepath.Join(tempDir, "review.json")
+	varsFile := filepath.Join(tempDir, "drone.env")
+	review := `{"reviews": [
+		{"file_path": "a.go", "line_number_start": 3, "line_number_end": 4, "type": "bug", "review": "nil dereference"},
+		{"file_path": "b.go", "line_number_start": 7, "line_number_end": 7, "type": "code_smell", "review": "duplicate logic"}
+	]}`
+	if err := os.WriteFile(reviewFile, []byte(review), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+	t.Setenv("DRONE_OUTPUT", varsFile)
+
+	var stdout, stderr strings.Builder
+	code := Run([]string{"publish", "-review-output-file", reviewFile}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "contains 2 comments") {
+		t.Errorf("Publish should summarize the comments, got:\n%s", stdout.String())
+	}
+
+	vars, err := os.ReadFile(varsFile)
+	if err != nil {
+		t.Fatalf("Output variables were not written: %v", err)
+	}
+	if !strings.Contains(string(vars), "REVIEW_COMMENT_COUNT=2\n") {
+		t.Errorf("Output variables should contain the comment count, got:\n%s", vars)
+	}
+
+	// Invalid reviews fail the step
+	if err := os.WriteFile(reviewFile, []byte(`{"reviews": [{"line_number_start": 5, "line_number_end": 2}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"publish", "-review-output-file", reviewFile}, &stdout, &stderr); code != 1 {
+		t.Errorf("Run() = %d for an invalid review, want 1", code)
+	}
+}
+
+func TestRunPublishMergesToolFindings(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n")
+	base := repo.commit("initial")
+	repo.write("main.go", "package main\n\nfunc main() {\n\tos.Remove(\"x\")\n}\n")
+	head := repo.commit("change")
+	t.Chdir(repo.dir)
+
+	tempDir := t.TempDir()
+	reviewFile := filepath.Join(tempDir, "review.json")
+	review := `{"reviews": [{"file_path": "main.go", "line_number_start": 3, "line_number_end": 3, "type": "bug", "review": "missing import"}]}`
+	if err := os.WriteFile(reviewFile, []byte(review), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+	report := filepath.Join(tempDir, "lint.xml")
+	checkstyle := `<checkstyle><file name="main.go">
+		<error line="4" severity="error" message="Error return value of os.Remove is not checked" source="errcheck"/>
+		<error line="1" severity="error" message="outside the diff" source="other"/>
+	</file></checkstyle>`
+	if err := os.WriteFile(report, []byte(checkstyle), 0644); err != nil {
+		t.Fatalf("Failed to write tool report: %v", err)
+	}
+
+	args := []string{"publish", "-review-output-file", reviewFile, "-merge-base-sha", base, "-source-sha", head,
+		"-tool-reports", report, "-merge-tool-findings", "-output-vars-file", ""}
+	var stdout, stderr strings.Builder
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Merged 1 tool findings") || !strings.Contains(stdout.String(), "static_analysis: 1") {
+		t.Errorf("Publish should report the merged finding, got:\n%s", stdout.String())
+	}
+
+	output, err := ReadReviewOutput(reviewFile)
+	if err != nil {
+		t.Fatalf("ReadReviewOutput() failed: %v", err)
+	}
+	if len(output.Reviews) != 2 || output.Reviews[1].LineNumberStart != 4 || output.Reviews[1].Type != StaticAnalysisType {
+		t.Errorf("Reviews = %+v, want the errcheck finding appended", output.Reviews)
+	}
+
+	// Publishing again does not duplicate the merged finding
+	stdout.Reset()
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if output, _ := ReadReviewOutput(reviewFile); len(output.Reviews) != 2 {
+		t.Errorf("Reviews = %+v, want no duplicates after a second publish", output.Reviews)
+	}
+}
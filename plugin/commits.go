@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// commitPathPrefix starts the pseudo file_path of findings about a commit message
+const commitPathPrefix = "commit:"
+
+// CommitHygieneType is the review type of commit findings merged into the review output
+const CommitHygieneType = "commit_hygiene"
+
+// CommitConventions configures the checks applied to every commit in the series
+type CommitConventions struct {
+	Conventional     bool
+	Types            []string
+	MaxSubjectLength int
+	RequireSignOff   bool
+}
+
+// CommitFinding is a convention violation detected in a commit message
+type CommitFinding struct {
+	SHA     string `json:"sha"`
+	Subject string `json:"subject"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ShortSHA returns the abbreviated hash of the offending commit
+func (f CommitFinding) ShortSHA() string {
+	return Commit{SHA: f.SHA}.ShortSHA()
+}
+
+// FilePath returns the pseudo-path under which the finding is reported
+func (f CommitFinding) FilePath() string {
+	return commitPathPrefix + f.ShortSHA()
+}
+
+// ReviewComment converts the finding into a comment on its commit pseudo-path
+func (f CommitFinding) ReviewComment() ReviewComment {
+	return ReviewComment{
+		FilePath:        f.FilePath(),
+		LineNumberStart: 1,
+		LineNumberEnd:   1,
+		Type:            CommitHygieneType,
+		Review:          fmt.Sprintf("**%s**: %s", f.Rule, f.Message),
+	}
+}
+
+// MergeCommitFindings appends the findings to the review output, skipping those
+// already reported on the same commit with the same text
+func MergeCommitFindings(output *ReviewOutput, findings []CommitFinding) int {
+	comments := make([]ReviewComment, 0, len(findings))
+	for _, f := range findings {
+		comments = append(comments, f.ReviewComment())
+	}
+	return output.AddComments(comments)
+}
+
+// CommitReview is the commit hygiene section of the prompt
+type CommitReview struct {
+	Rules    []string        `json:"rules"`
+	Findings []CommitFinding `json:"findings"`
+}
+
+// vagueSubjects are commit subjects that say nothing about the change
+var vagueSubjects = map[string]bool{
+	"wip": true, "fix": true, "fixes": true, "fixed": true, "update": true, "updates": true,
+	"changes": true, "change": true, "misc": true, "tmp": true, "temp": true, "stuff": true,
+	"cleanup": true, "minor": true, "test": true, "tests": true, "refactor": true,
+}
+
+// NewCommitConventions builds the conventions configured in settings
+func NewCommitConventions(settings Settings) CommitConventions {
+	conventions := CommitConventions{
+		Conventional:     strings.EqualFold(settings.CommitConvention, "conventional"),
+		MaxSubjectLength: settings.CommitMaxSubjectLength,
+		RequireSignOff:   settings.CommitRequireSignOff,
+	}
+	if !conventions.Conventional {
+		return conventions
+	}
+	for _, t := range strings.Split(settings.CommitTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			conventions.Types = append(conventions.Types, t)
+		}
+	}
+	return conventions
+}
+
+// Describe lists the conventions in a form suitable for the prompt
+func (c CommitConventions) Describe() []string {
+	var rules []string
+	if c.Conventional {
+		rules = append(rules, fmt.Sprintf("Subjects follow Conventional Commits: <type>(<scope>): <description>, with type one of %s", strings.Join(c.Types, ", ")))
+	}
+	if c.MaxSubjectLength > 0 {
+		rules = append(rules, fmt.Sprintf("Subjects are at most %d characters long", c.MaxSubjectLength))
+	}
+	if c.RequireSignOff {
+		rules = append(rules, "Every commit carries a Signed-off-by trailer")
+	}
+	return rules
+}
+
+// conventionalPattern matches "type(scope)!: description" and captures the type
+var conventionalPattern = regexp.MustCompile(`^([a-zA-Z]+)(\([^()\s]+\))?!?: \S`)
+
+// CheckCommits applies the conventions to each commit and returns the violations
+func CheckCommits(commits []Commit, conventions CommitConventions) []CommitFinding {
+	var findings []CommitFinding
+	for _, c := range commits {
+		add := func(rule, format string, args ...any) {
+			findings = append(findings, CommitFinding{
+				SHA:     c.SHA,
+				Subject: c.Subject,
+				Rule:    rule,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		subject := strings.TrimSpace(c.Subject)
+		normalized := strings.ToLower(strings.TrimRight(subject, ".!"))
+		switch {
+		case strings.HasPrefix(normalized, "fixup!") || strings.HasPrefix(normalized, "squash!"):
+			add("autosquash", "Commit %q is an autosquash commit and should be squashed before merging.", subject)
+		case vagueSubjects[normalized] || strings.HasPrefix(normalized, "wip "):
+			add("vague-subject", "Commit subject %q does not describe the change.", subject)
+		}
+
+		if conventions.Conventional {
+			m := conventionalPattern.FindStringSubmatch(subject)
+			if m == nil {
+				add("conventional-commits", "Commit subject %q does not follow the Conventional Commits format <type>(<scope>): <description>.", subject)
+			} else if len(conventions.Types) > 0 && !containsFold(conventions.Types, m[1]) {
+				add("conventional-commits", "Commit type %q is not one of the allowed types: %s.", m[1], strings.Join(conventions.Types, ", "))
+			}
+		}
+
+		if conventions.MaxSubjectLength > 0 && len([]rune(subject)) > conventions.MaxSubjectLength {
+			add("subject-length", "Commit subject is %d characters long; the limit is %d.", len([]rune(subject)), conventions.MaxSubjectLength)
+		}
+
+		if conventions.RequireSignOff && !strings.Contains(c.Body, "Signed-off-by: ") {
+			add("sign-off", "Commit %q is missing a Signed-off-by trailer.", subject)
+		}
+	}
+	return findings
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCommitConventions(t *testing.T) {
+	settings := Settings{CommitConvention: "Conventional", CommitTypes: "feat, fix,,docs", CommitMaxSubjectLength: 50, CommitRequireSignOff: true}
+	got := NewCommitConventions(settings)
+	want := CommitConventions{Conventional: true, Types: []string{"feat", "fix", "docs"}, MaxSubjectLength: 50, RequireSignOff: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewCommitConventions() = %+v, want %+v", got, want)
+	}
+	if rules := got.Describe(); len(rules) != 3 {
+		t.Errorf("Describe() = %q, want 3 rules", rules)
+	}
+
+	got = NewCommitConventions(Settings{CommitConvention: "none", CommitTypes: "feat"})
+	if got.Conventional || got.Types != nil || len(got.Describe()) != 0 {
+		t.Errorf("NewCommitConventions(none) = %+v, want no conventions", got)
+	}
+}
+
+func TestCheckCommits(t *testing.T) {
+	conventions := CommitConventions{
+		Conventional:     true,
+		Types:            []string{"feat", "fix"},
+		MaxSubjectLength: 30,
+		RequireSignOff:   true,
+	}
+	signedOff := "Signed-off-by: Dev <dev@example.com>"
+
+	tests := []struct {
+		name   string
+		commit Commit
+		want   []string
+	}{
+		{"valid", Commit{Subject: "feat(api): add retries", Body: signedOff}, nil},
+		{"breaking change", Commit{Subject: "fix!: drop v1 endpoint", Body: signedOff}, nil},
+		{"vague", Commit{Subject: "WIP", Body: signedOff}, []string{"vague-subject", "conventional-commits"}},
+		{"vague with period", Commit{Subject: "fix.", Body: signedOff}, []string{"vague-subject", "conventional-commits"}},
+		{"autosquash", Commit{Subject: "fixup! feat: add retries", Body: signedOff}, []string{"autosquash", "conventional-commits"}},
+		{"unknown type", Commit{Subject: "chore: bump deps", Body: signedOff}, []string{"conventional-commits"}},
+		{"too long", Commit{Subject: "feat: add a much longer subject line", Body: signedOff}, []string{"subject-length"}},
+		{"missing sign-off", Commit{Subject: "feat: add retries"}, []string{"sign-off"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.commit.SHA = "0123456789abcdef"
+			var rules []string
+			for _, f := range CheckCommits([]Commit{tt.commit}, conventions) {
+				rules = append(rules, f.Rule)
+				if f.FilePath() != "commit:01234567" {
+					t.Errorf("FilePath() = %q, want commit:01234567", f.FilePath())
+				}
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("CheckCommits(%q) rules = %q, want %q", tt.commit.Subject, rules, tt.want)
+			}
+		})
+	}
+
+	// Vague subjects are flagged even when no convention is configured
+	findings := CheckCommits([]Commit{{Subject: "update"}, {Subject: "Add retry loop to uploader"}}, CommitConventions{})
+	if len(findings) != 1 || findings[0].Rule != "vague-subject" {
+		t.Errorf("CheckCommits() without conventions = %+v, want one vague-subject finding", findings)
+	}
+}
+
+func TestRunPublishMergesCommitFindings(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n")
+	base := repo.commit("initial")
+	repo.write("main.go", "package main\n\nfunc main() {}\n")
+	head := repo.commit("wip")
+	t.Chdir(repo.dir)
+
+	reviewFile := filepath.Join(t.TempDir(), "review.json")
+	if err := os.WriteFile(reviewFile, []byte(`{"reviews": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	args := []string{"publish", "-review-output-file", reviewFile, "-merge-base-sha", base, "-source-sha", head,
+		"-enable-commit-review", "-output-vars-file", ""}
+	var stdout, stderr strings.Builder
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Merged 1 commit findings") {
+		t.Errorf("Publish should report the merged finding, got:\n%s", stdout.String())
+	}
+
+	output, err := ReadReviewOutput(reviewFile)
+	if err != nil {
+		t.Fatalf("ReadReviewOutput() failed: %v", err)
+	}
+	want := ReviewComment{
+		FilePath:        "commit:" + head[:8],
+		LineNumberStart: 1,
+		LineNumberEnd:   1,
+		Type:            CommitHygieneType,
+		Review:          "**vague-subject**: Commit subject \"wip\" does not describe the change.",
+	}
+	if len(output.Reviews) != 1 || output.Reviews[0] != want {
+		t.Errorf("Reviews = %+v, want the wip commit reported", output.Reviews)
+	}
+
+	// Publishing again does not duplicate the merged finding
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if output, _ := ReadReviewOutput(reviewFile); len(output.Reviews) != 1 {
+		t.Errorf("Reviews = %+v, want no duplicates after a second publish", output.Reviews)
+	}
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"fmt"
+)
+
+// ReviewContext holds the information gathered from the repository that the
+// prompt template renders alongside the settings
+type ReviewContext struct {
+	Files        []ChangedFile
+	Diffs        []FileDiff
+	Overview     *DiffOverview
+	MissingTests []MissingTest
+	Coverage     *CoverageReport
+	ToolFindings *ToolFindings
+	Dependencies *DependencyReview
+	Migrations   *MigrationReview
+	APIChanges   *APIReview
+	Functions    []FunctionContext
+	Callers      *CallerReview
+	Languages    []LanguageGuidance
+	PullRequest  *PullRequestInfo
+	Commits      *CommitReview
+
+	// Trim records what was omitted to fit max_tokens and Tokens the final prompt size
+	Trim   *TrimReport
+	Tokens *TokenReport
+
+	// Warnings report optional context that could not be gathered
+	Warnings []string
+}
+
+// CollectContext inspects the diff between the merge base and source SHA in dir.
+// When the changed files cannot be listed it returns the error along with a
+// context holding only the pull request text, which does not depend on git.
+func CollectContext(dir string, settings Settings) (*ReviewContext, error) {
+	files, err := ListChangedFiles(dir, settings.MergeBaseSha, settings.SourceSha)
+	if err != nil {
+		// Commits cannot be listed either, so only the title, description and ticket are read
+		prSettings := settings
+		prSettings.IncludeCommitLog, prSettings.EnableCommitReview = false, false
+		ctx := &ReviewContext{}
+		ctx.PullRequest, ctx.Warnings = CollectPullRequestInfo(dir, prSettings)
+		return ctx, fmt.Errorf("could not determine changed files: %w", err)
+	}
+
+	ctx := &ReviewContext{Files: files}
+	if settings.EnableDiffOverview || settings.EnableMigrationReview || settings.EnableFunctionContext || settings.EnableCallerContext || settings.MaxTokens > 0 || settings.CoverageFile != "" || settings.ToolReports != "" {
+		diffs, err := LoadDiff(dir, settings.MergeBaseSha, settings.SourceSha)
+		if err != nil {
+			ctx.Warnings = append(ctx.Warnings, err.Error())
+		}
+		ctx.Diffs = diffs
+	}
+	if settings.EnableDiffOverview {
+		ctx.Overview = AnalyzeRisk(files, ctx.Diffs)
+	}
+	if settings.CoverageFile != "" {
+		coverage, err := ReadCoverage(settings.CoverageFile, settings.CoverageFormat)
+		if err != nil {
+			ctx.Warnings = append(ctx.Warnings, err.Error())
+		} else {
+			coverage.Module = goModulePath(dir, settings.SourceSha)
+			ctx.Coverage = coverage.Uncovered(settings.CoverageFile, ctx.Diffs)
+		}
+	}
+	if settings.ToolReports != "" {
+		findings, warnings := CollectToolFindings(settings.ToolReports, ctx.Diffs)
+		ctx.Warnings = append(ctx.Warnings, warnings...)
+		ctx.ToolFindings = NewToolFindings(findings)
+	}
+	if settings.EnableDependencyReview {
+		ctx.Dependencies = AnalyzeDependencies(dir, settings.MergeBaseSha, settings.SourceSha, files)
+	}
+	if settings.EnableMigrationReview {
+		globs, err := ParseMigrationGlobs(settings.MigrationGlobs)
+		if err != nil {
+			ctx.Warnings = append(ctx.Warnings, fmt.Sprintf("migration_globs: %v", err))
+			globs = defaultMigrationGlobs
+		}
+		ctx.Migrations = FindMigrations(files, ctx.Diffs, globs)
+	}
+	if settings.EnableAPICompat {
+		ctx.APIChanges = AnalyzeAPICompat(dir, settings.MergeBaseSha, settings.SourceSha, files)
+	}
+	if settings.EnableFunctionContext {
+		ctx.Functions = ExtractFunctionContext(dir, settings.SourceSha, files, ctx.Diffs, settings.FunctionContextMaxLines)
+	}
+	if settings.EnableCallerContext {
+		ctx.Callers = FindCallers(dir, settings.SourceSha, files, ctx.Diffs)
+	}
+	if settings.EnableMissingTests {
+		patterns, err := ParseTestPatterns(settings.TestPatterns)
+		if err != nil {
+			ctx.Warnings = append(ctx.Warnings, fmt.Sprintf("test_patterns: %v", err))
+		}
+		if patterns == nil {
+			patterns = defaultTestPatterns
+		}
+		ctx.MissingTests = FindMissingTests(files, patterns)
+	}
+	if settings.EnableLanguageGuidance {
+		ctx.Languages = DetectLanguages(dir, settings.SourceSha, files, settings.LanguageRulesPath)
+	}
+
+	var warnings []string
+	ctx.PullRequest, warnings = CollectPullRequestInfo(dir, settings)
+	ctx.Warnings = append(ctx.Warnings, warnings...)
+
+	if settings.EnableCommitReview && ctx.PullRequest != nil {
+		conventions := NewCommitConventions(settings)
+		ctx.Commits = &CommitReview{
+			Rules:    conventions.Describe(),
+			Findings: CheckCommits(ctx.PullRequest.Commits, conventions),
+		}
+	}
+	return ctx, nil
+}
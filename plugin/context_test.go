@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func TestCollectContext(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n")
+	base := repo.commit("initial")
+	repo.write("main.go", "package main\n\nfunc main() {}\n")
+	repo.write("app.py", "print('hi')\n")
+	head := repo.commit("change")
+
+	settings := Settings{MergeBaseSha: base, SourceSha: head, EnableLanguageGuidance: true, EnableDiffOverview: true, EnableMissingTests: true}
+	ctx, err := CollectContext(repo.dir, settings)
+	if err != nil {
+		t.Fatalf("CollectContext() failed: %v", err)
+	}
+	if len(ctx.Files) != 2 {
+		t.Errorf("Files = %+v, want 2 changed files", ctx.Files)
+	}
+	if len(ctx.Diffs) != 2 || ctx.Overview == nil || len(ctx.Overview.Files) != 2 {
+		t.Errorf("Diffs = %+v, Overview = %+v, want both files parsed and scored", ctx.Diffs, ctx.Overview)
+	}
+	if len(ctx.Languages) != 2 {
+		t.Errorf("Languages = %+v, want Go and Python", ctx.Languages)
+	}
+	if len(ctx.MissingTests) != 2 {
+		t.Errorf("MissingTests = %+v, want main.go and app.py", ctx.MissingTests)
+	}
+
+	settings.EnableLanguageGuidance = false
+	ctx, err = CollectContext(repo.dir, settings)
+	if err != nil {
+		t.Fatalf("CollectContext() failed: %v", err)
+	}
+	if len(ctx.Languages) != 0 {
+		t.Errorf("Languages = %+v, want none when language guidance is disabled", ctx.Languages)
+	}
+
+	if ctx.Commits != nil {
+		t.Errorf("Commits = %+v, want nil when commit review is disabled", ctx.Commits)
+	}
+
+	settings.EnableCommitReview = true
+	settings.CommitMaxSubjectLength = 72
+	ctx, err = CollectContext(repo.dir, settings)
+	if err != nil {
+		t.Fatalf("CollectContext() failed: %v", err)
+	}
+	if ctx.Commits == nil || len(ctx.Commits.Findings) != 1 || ctx.Commits.Findings[0].Rule != "vague-subject" {
+		t.Errorf("Commits = %+v, want one vague-subject finding for the \"change\" commit", ctx.Commits)
+	}
+
+	// Without a usable diff the pull request text is still collected
+	ctx, err = CollectContext(repo.dir, Settings{MergeBaseSha: "missing", SourceSha: head, PRTitle: "Add login", PRDescription: "Adds the login page.", IncludeCommitLog: true})
+	if err == nil {
+		t.Error("CollectContext() should fail for an unknown merge base")
+	}
+	if ctx == nil || ctx.PullRequest == nil || ctx.PullRequest.Title != "Add login" || ctx.PullRequest.Description != "Adds the login page." || len(ctx.Files) != 0 {
+		t.Errorf("CollectContext() = %+v, want only the pull request text", ctx)
+	}
+}
+
+func TestCollectContextCoverage(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n")
+	base := repo.commit("initial")
+	repo.write("main.go", "package main\n\nfunc main() {\n\tprintln()\n}\n")
+	head := repo.commit("change")
+
+	profile := writeCoverageFile(t, "coverage.out", "mode: set\nexample.com/app/main.go:3.13,5.2 1 0\n")
+	settings := Settings{MergeBaseSha: base, SourceSha: head, CoverageFile: profile, CoverageFormat: "auto"}
+	ctx, err := CollectContext(repo.dir, settings)
+	if err != nil {
+		t.Fatalf("CollectContext() failed: %v", err)
+	}
+	if ctx.Coverage == nil || ctx.Coverage.Uncovered != 3 || len(ctx.Coverage.Files) != 1 {
+		t.Errorf("Coverage = %+v, want lines 3-5 of main.go uncovered", ctx.Coverage)
+	}
+
+	settings.CoverageFile = profile + ".missing"
+	ctx, err = CollectContext(repo.dir, settings)
+	if err != nil {
+		t.Fatalf("CollectContext() failed: %v", err)
+	}
+	if ctx.Coverage != nil || len(ctx.Warnings) != 1 {
+		t.Errorf("Coverage = %+v, Warnings = %q; want a warning for the missing report", ctx.Coverage, ctx.Warnings)
+	}
+}
@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported coverage report formats
+const (
+	coverageAuto      = "auto"
+	coverageGo        = "go"
+	coverageLCOV      = "lcov"
+	coverageCobertura = "cobertura"
+)
+
+// Coverage records, per file in the report, whether each executable line ran
+type Coverage struct {
+	Format string
+	// Module is the Go module path of the repository; Go cover profiles name
+	// files <module>/<repository path>
+	Module string
+	files  map[string]map[int]bool
+}
+
+// UncoveredFile lists the changed lines of a file that no test executed
+type UncoveredFile struct {
+	Path       string `json:"path"`
+	Lines      []int  `json:"lines"`
+	Executable int    `json:"executable_changed_lines"`
+}
+
+// Ranges formats the uncovered lines as compact ranges such as "12-18, 40"
+func (u UncoveredFile) Ranges() string {
+	var parts []string
+	for i := 0; i < len(u.Lines); {
+		j := i
+		for j+1 < len(u.Lines) && u.Lines[j+1] == u.Lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(u.Lines[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", u.Lines[i], u.Lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CoverageReport is the coverage section of the prompt
+type CoverageReport struct {
+	File       string          `json:"file"`
+	Format     string          `json:"format"`
+	Executable int             `json:"executable_changed_lines"`
+	Uncovered  int             `json:"uncovered_changed_lines"`
+	Files      []UncoveredFile `json:"files"`
+}
+
+// ReadCoverage loads a Go cover profile, LCOV tracefile or Cobertura XML report.
+// With format "auto" or "" the format is detected from the content.
+func ReadCoverage(file, format string) (*Coverage, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read coverage_file: %w", err)
+	}
+	if format == "" || format == coverageAuto {
+		format = detectCoverageFormat(content)
+	}
+
+	c := &Coverage{Format: format, files: make(map[string]map[int]bool)}
+	switch format {
+	case coverageGo:
+		err = c.parseGoProfile(content)
+	case coverageLCOV:
+		err = c.parseLCOV(content)
+	case coverageCobertura:
+		err = c.parseCobertura(content)
+	default:
+		return nil, fmt.Errorf("could not detect the format of coverage file %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s coverage file %s: %w", format, file, err)
+	}
+	return c, nil
+}
+
+// detectCoverageFormat guesses the report format from its first meaningful line
+func detectCoverageFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return coverageGo
+	case bytes.HasPrefix(trimmed, []byte("TN:")), bytes.HasPrefix(trimmed, []byte("SF:")):
+		return coverageLCOV
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return coverageCobertura
+	}
+	return ""
+}
+
+// record marks a line as executable and, if count is positive, as covered
+func (c *Coverage) record(file string, line, count int) {
+	lines, ok := c.files[file]
+	if !ok {
+		lines = make(map[int]bool)
+		c.files[file] = lines
+	}
+	lines[line] = lines[line] || count > 0
+}
+
+// parseGoProfile reads "file:startLine.startCol,endLine.endCol statements count" blocks
+func (c *Coverage) parseGoProfile(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return fmt.Errorf("malformed profile line %q", line)
+		}
+		var startLine, startCol, endLine, endCol, statements, count int
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d", &startLine, &startCol, &endLine, &endCol, &statements, &count); err != nil {
+			return fmt.Errorf("malformed profile line %q: %w", line, err)
+		}
+		for l := startLine; l <= endLine; l++ {
+			c.record(line[:colon], l, count)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseLCOV reads the SF: and DA: records of an LCOV tracefile
+func (c *Coverage) parseLCOV(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	file := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file = strings.TrimPrefix(line, "SF:")
+		case strings.HasPrefix(line, "DA:"):
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if file == "" || len(fields) < 2 {
+				return fmt.Errorf("malformed record %q", line)
+			}
+			number, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return fmt.Errorf("malformed record %q: %w", line, err)
+			}
+			count, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("malformed record %q: %w", line, err)
+			}
+			c.record(file, number, count)
+		case line == "end_of_record":
+			file = ""
+		}
+	}
+	return scanner.Err()
+}
+
+// coberturaReport is the subset of a Cobertura XML report the plugin reads
+type coberturaReport struct {
+	Sources []string `xml:"sources>source"`
+	Classes []struct {
+		Filename string `xml:"filename,attr"`
+		Lines    []struct {
+			Number int `xml:"number,attr"`
+			Hits   int `xml:"hits,attr"`
+		} `xml:"lines>line"`
+	} `xml:"packages>package>classes>class"`
+}
+
+// parseCobertura reads the per-class line hits of a Cobertura XML report
+func (c *Coverage) parseCobertura(content []byte) error {
+	var report coberturaReport
+	if err := xml.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	for _, class := range report.Classes {
+		// Filenames are relative to one of the source roots, so the class is
+		// recorded under each of them
+		files := []string{class.Filename}
+		if len(report.Sources) > 0 && !path.IsAbs(class.Filename) {
+			files = files[:0]
+			for _, source := range report.Sources {
+				files = append(files, path.Join(strings.TrimSpace(source), class.Filename))
+			}
+		}
+		for _, file := range files {
+			for _, line := range class.Lines {
+				c.record(file, line.Number, line.Hits)
+			}
+		}
+	}
+	return nil
+}
+
+// lines returns the coverage of a repository path. A Go cover profile names it
+// <module>/<repoPath> when the module is known. Otherwise, such as for the
+// absolute paths of an LCOV tracefile or Cobertura source root, the one report
+// path ending in /<repoPath> is used; when several do, the match is ambiguous
+// and nil is returned.
+func (c *Coverage) lines(repoPath string) map[int]bool {
+	if lines, ok := c.files[repoPath]; ok {
+		return lines
+	}
+	if c.Format == coverageGo && c.Module != "" {
+		return c.files[path.Join(c.Module, repoPath)]
+	}
+	var match map[int]bool
+	for file, lines := range c.files {
+		if !strings.HasSuffix(file, "/"+repoPath) {
+			continue
+		}
+		if match != nil {
+			return nil
+		}
+		match = lines
+	}
+	return match
+}
+
+// Uncovered intersects the report with the added lines of each file and
+// returns the changed lines the tests did not execute
+func (c *Coverage) Uncovered(file string, diffs []FileDiff) *CoverageReport {
+	report := &CoverageReport{File: file, Format: c.Format}
+	for _, d := range diffs {
+		lines := c.lines(d.Path)
+		if lines == nil {
+			continue
+		}
+		u := UncoveredFile{Path: d.Path}
+		for _, l := range d.AddedLines() {
+			covered, executable := lines[l]
+			if !executable {
+				continue
+			}
+			u.Executable++
+			if !covered {
+				u.Lines = append(u.Lines, l)
+			}
+		}
+		report.Executable += u.Executable
+		report.Uncovered += len(u.Lines)
+		if len(u.Lines) > 0 {
+			report.Files = append(report.Files, u)
+		}
+	}
+	sort.SliceStable(report.Files, func(i, j int) bool {
+		return len(report.Files[i].Lines) > len(report.Files[j].Lines)
+	})
+	return report
+}
@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// coverageDiff adds lines 10-14 and 20 to plugin/cli.go
+var coverageDiff = []FileDiff{{
+	Path: "plugin/cli.go",
+	Hunks: []Hunk{
+		{NewStart: 10, Lines: []DiffLine{
+			{Kind: '+', NewLine: 10}, {Kind: '+', NewLine: 11}, {Kind: '+', NewLine: 12},
+			{Kind: '+', NewLine: 13}, {Kind: '+', NewLine: 14},
+		}},
+		{NewStart: 20, Lines: []DiffLine{{Kind: '+', NewLine: 20}}},
+	},
+}, {
+	Path:  "README.md",
+	Hunks: []Hunk{{NewStart: 1, Lines: []DiffLine{{Kind: '+', NewLine: 1}}}},
+}}
+
+func writeCoverageFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write coverage file: %v", err)
+	}
+	return file
+}
+
+func TestReadCoverage(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		wantFormat string
+	}{
+		{
+			name: "go profile",
+			file: "coverage.out",
+			content: `mode: set
+example.com/app/plugin/cli.go:10.20,12.3 2 1
+example.com/app/plugin/cli.go:12.3,14.3 1 0
+example.com/app/plugin/cli.go:20.2,20.10 1 0
+`,
+			wantFormat: coverageGo,
+		},
+		{
+			name: "lcov",
+			file: "lcov.info",
+			content: `TN:
+SF:/drone/src/plugin/cli.go
+DA:10,1
+DA:11,3
+DA:12,1
+DA:13,0
+DA:14,0
+DA:20,0
+end_of_record
+`,
+			wantFormat: coverageLCOV,
+		},
+		{
+			name: "cobertura",
+			file: "coverage.xml",
+			content: `<?xml version="1.0" ?>
+<coverage line-rate="0.5">
+  <sources><source>plugin</source></sources>
+  <packages><package name="plugin"><classes>
+    <class name="cli" filename="cli.go">
+      <lines>
+        <line number="10" hits="2"/>
+        <line number="11" hits="2"/>
+        <line number="12" hits="1"/>
+        <line number="13" hits="0"/>
+        <line number="14" hits="0"/>
+        <line number="20" hits="0"/>
+      </lines>
+    </class>
+  </classes></package></packages>
+</coverage>
+`,
+			wantFormat: coverageCobertura,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverage, err := ReadCoverage(writeCoverageFile(t, tt.file, tt.content), coverageAuto)
+			if err != nil {
+				t.Fatalf("ReadCoverage() failed: %v", err)
+			}
+			if coverage.Format != tt.wantFormat {
+				t.Errorf("Format = %q, want %q", coverage.Format, tt.wantFormat)
+			}
+
+			report := coverage.Uncovered(tt.file, coverageDiff)
+			want := []UncoveredFile{{Path: "plugin/cli.go", Lines: []int{13, 14, 20}, Executable: 6}}
+			if !reflect.DeepEqual(report.Files, want) {
+				t.Errorf("Uncovered() files = %+v, want %+v", report.Files, want)
+			}
+			if report.Executable != 6 || report.Uncovered != 3 {
+				t.Errorf("Uncovered() = %d of %d lines, want 3 of 6", report.Uncovered, report.Executable)
+			}
+		})
+	}
+}
+
+func TestReadCoverageErrors(t *testing.T) {
+	if _, err := ReadCoverage(filepath.Join(t.TempDir(), "missing.out"), coverageAuto); err == nil {
+		t.Error("ReadCoverage() should fail for a missing file")
+	}
+	if _, err := ReadCoverage(writeCoverageFile(t, "unknown.txt", "hello\n"), coverageAuto); err == nil {
+		t.Error("ReadCoverage() should fail for an unrecognized format")
+	}
+	if _, err := ReadCoverage(writeCoverageFile(t, "bad.out", "mode: set\nbroken line\n"), coverageGo); err == nil {
+		t.Error("ReadCoverage() should fail for a malformed Go profile")
+	}
+	if _, err := ReadCoverage(writeCoverageFile(t, "bad.info", "SF:a.go\nDA:x,1\n"), coverageLCOV); err == nil {
+		t.Error("ReadCoverage() should fail for a malformed LCOV record")
+	}
+}
+
+func TestUncoveredFileRanges(t *testing.T) {
+	u := UncoveredFile{Lines: []int{3, 12, 13, 14, 18, 40, 41}}
+	if got := u.Ranges(); got != "3, 12-14, 18, 40-41" {
+		t.Errorf("Ranges() = %q, want %q", got, "3, 12-14, 18, 40-41")
+	}
+}
+
+func TestCoverageLinesResolve(t *testing.T) {
+	files := map[string]map[int]bool{
+		"/src/vendor/example.com/lib/util.go": {1: false},
+		"example.com/app/util.go":             {1: true},
+		"example.com/lib/util.go":             {1: false},
+		"example.com/app/cmd/a/main.go":       {3: true},
+		"README.go":                           {2: true},
+	}
+
+	// A Go profile is resolved through the module path only
+	c := &Coverage{Format: coverageGo, Module: "example.com/app", files: files}
+	if lines := c.lines("util.go"); !lines[1] {
+		t.Errorf("lines(util.go) = %v, want example.com/app/util.go", lines)
+	}
+	if lines := c.lines("main.go"); lines != nil {
+		t.Errorf("lines(main.go) = %v, want nil rather than the coverage of cmd/a/main.go", lines)
+	}
+	if lines := c.lines("README.go"); !lines[2] {
+		t.Errorf("lines(README.go) = %v, want the exact match", lines)
+	}
+
+	// Without a module a single suffix match is used and several are ambiguous
+	c = &Coverage{Format: coverageLCOV, files: files}
+	if lines := c.lines("a/main.go"); !lines[3] {
+		t.Errorf("lines(a/main.go) = %v, want the only suffix match", lines)
+	}
+	if lines := c.lines("util.go"); lines != nil {
+		t.Errorf("lines(util.go) = %v, want nil for an ambiguous match", lines)
+	}
+	if lines := c.lines("other.go"); lines != nil {
+		t.Errorf("lines(other.go) = %v, want nil", lines)
+	}
+}
+
+func TestReadCoverageCoberturaSources(t *testing.T) {
+	report := `<coverage><sources><source>/drone/src/api</source><source>/drone/src/web</source></sources>
+  <packages><package><classes>
+    <class filename="server.go"><lines><line number="10" hits="0"/></lines></class>
+    <class filename="app.go"><lines><line number="4" hits="1"/></lines></class>
+  </classes></package></packages></coverage>`
+	coverage, err := ReadCoverage(writeCoverageFile(t, "coverage.xml", report), coverageAuto)
+	if err != nil {
+		t.Fatalf("ReadCoverage() failed: %v", err)
+	}
+	if lines := coverage.lines("api/server.go"); lines == nil || lines[10] {
+		t.Errorf("lines(api/server.go) = %v, want line 10 uncovered", lines)
+	}
+	if lines := coverage.lines("web/app.go"); !lines[4] {
+		t.Errorf("lines(web/app.go) = %v, want line 4 covered", lines)
+	}
+}
@@ -0,0 +1,365 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of dependency change
+const (
+	depAdded      = "added"
+	depRemoved    = "removed"
+	depUpgraded   = "upgraded"
+	depDowngraded = "downgraded"
+	depChanged    = "changed"
+)
+
+// DependencyChange is one entry of the dependency delta between the two commits
+type DependencyChange struct {
+	Manifest   string `json:"manifest"`
+	Name       string `json:"name"`
+	Change     string `json:"change"`
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	Major      bool   `json:"major,omitempty"`
+}
+
+// Describe formats the change for the prompt
+func (d DependencyChange) Describe() string {
+	switch d.Change {
+	case depAdded:
+		return fmt.Sprintf("added %s %s", d.Name, d.NewVersion)
+	case depRemoved:
+		return fmt.Sprintf("removed %s %s", d.Name, d.OldVersion)
+	}
+	text := fmt.Sprintf("%s %s %s -> %s", d.Change, d.Name, d.OldVersion, d.NewVersion)
+	if d.Major {
+		text += " (major version bump)"
+	}
+	return text
+}
+
+// DependencyReview is the dependency section of the prompt
+type DependencyReview struct {
+	Changes []DependencyChange `json:"changes"`
+	Notes   []string           `json:"notes,omitempty"`
+}
+
+// dependencyManifest knows how to read one kind of manifest and which lockfiles accompany it
+type dependencyManifest struct {
+	match     func(name string) bool
+	parse     func(content string) map[string]string
+	lockfiles []string
+}
+
+// dependencyManifests are the manifest formats the plugin understands
+var dependencyManifests = []dependencyManifest{
+	{
+		match:     func(name string) bool { return name == "go.mod" },
+		parse:     parseGoMod,
+		lockfiles: []string{"go.sum"},
+	},
+	{
+		match:     func(name string) bool { return name == "package.json" },
+		parse:     parsePackageJSON,
+		lockfiles: []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "npm-shrinkwrap.json", "bun.lockb"},
+	},
+	{
+		match: func(name string) bool {
+			return strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt")
+		},
+		parse: parseRequirements,
+	},
+	{
+		match:     func(name string) bool { return name == "Cargo.toml" },
+		parse:     parseCargoToml,
+		lockfiles: []string{"Cargo.lock"},
+	},
+}
+
+// findDependencyManifest returns the parser for a manifest path, or nil
+func findDependencyManifest(p string) *dependencyManifest {
+	name := path.Base(p)
+	for i := range dependencyManifests {
+		if dependencyManifests[i].match(name) {
+			return &dependencyManifests[i]
+		}
+	}
+	return nil
+}
+
+// AnalyzeDependencies parses every changed manifest at base and head and returns
+// the dependency delta, or nil when no manifest changed a dependency
+func AnalyzeDependencies(dir, base, head string, files []ChangedFile) *DependencyReview {
+	changed := make(map[string]bool)
+	for _, f := range files {
+		changed[f.Path] = true
+	}
+
+	review := &DependencyReview{}
+	for _, f := range files {
+		manifest := findDependencyManifest(f.Path)
+		if manifest == nil {
+			continue
+		}
+		oldPath := f.Path
+		if f.OldPath != "" {
+			oldPath = f.OldPath
+		}
+
+		var before, after map[string]string
+		if f.Status != "A" {
+			before = manifest.parse(fileAt(dir, base, oldPath))
+		}
+		if f.Status != "D" {
+			after = manifest.parse(fileAt(dir, head, f.Path))
+		}
+		changes := diffDependencies(f.Path, before, after)
+		review.Changes = append(review.Changes, changes...)
+
+		if len(changes) > 0 && len(manifest.lockfiles) > 0 && f.Status != "D" {
+			if !anyLockfileChanged(path.Dir(f.Path), manifest.lockfiles, changed) {
+				review.Notes = append(review.Notes, fmt.Sprintf("%s changed dependencies but none of its lockfiles (%s) changed", f.Path, strings.Join(manifest.lockfiles, ", ")))
+			}
+		}
+	}
+	if len(review.Changes) == 0 {
+		return nil
+	}
+	return review
+}
+
+// fileAt returns the content of a file at sha, or "" when it does not exist
+func fileAt(dir, sha, filePath string) string {
+	out, err := runGit(dir, "show", sha+":"+filePath)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// goModulePath returns the module path declared by the go.mod at the root of
+// the repository at sha, or "" when there is none
+func goModulePath(dir, sha string) string {
+	for _, line := range strings.Split(fileAt(dir, sha, "go.mod"), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// anyLockfileChanged reports whether a lockfile next to the manifest, or at the repository root, changed
+func anyLockfileChanged(dir string, lockfiles []string, changed map[string]bool) bool {
+	for _, lock := range lockfiles {
+		if changed[path.Join(dir, lock)] || changed[lock] {
+			return true
+		}
+	}
+	return false
+}
+
+// goMajorSuffix matches the /vN suffix of a Go module path for major versions 2 and above
+var goMajorSuffix = regexp.MustCompile(`/v[2-9][0-9]*$`)
+
+// diffDependencies compares two name-to-version maps of the same manifest
+func diffDependencies(manifest string, before, after map[string]string) []DependencyChange {
+	var changes []DependencyChange
+
+	// A Go module moving to /vN is an upgrade of the same dependency, not a swap
+	renamed := make(map[string]string)
+	if path.Base(manifest) == "go.mod" {
+		for name := range after {
+			if _, ok := before[name]; ok {
+				continue
+			}
+			for oldName := range before {
+				if _, ok := after[oldName]; !ok && goMajorSuffix.ReplaceAllString(oldName, "") == goMajorSuffix.ReplaceAllString(name, "") {
+					renamed[name] = oldName
+				}
+			}
+		}
+	}
+	renamedFrom := make(map[string]bool)
+	for _, oldName := range renamed {
+		renamedFrom[oldName] = true
+	}
+
+	for name, newVersion := range after {
+		oldName := name
+		if r, ok := renamed[name]; ok {
+			oldName = r
+		}
+		oldVersion, existed := before[oldName]
+		if !existed {
+			changes = append(changes, DependencyChange{Manifest: manifest, Name: name, Change: depAdded, NewVersion: newVersion})
+			continue
+		}
+		if oldVersion == newVersion && oldName == name {
+			continue
+		}
+
+		c := DependencyChange{Manifest: manifest, Name: name, OldVersion: oldVersion, NewVersion: newVersion, Change: depChanged}
+		if oldName != name {
+			c.Name = oldName + " => " + name
+		}
+		oldParts, okOld := parseVersion(oldVersion)
+		newParts, okNew := parseVersion(newVersion)
+		if okOld && okNew {
+			switch cmp := compareVersions(oldParts, newParts); {
+			case cmp < 0:
+				c.Change = depUpgraded
+			case cmp > 0:
+				c.Change = depDowngraded
+			}
+			c.Major = oldParts[0] != newParts[0] || oldParts[0] == 0 && oldParts[1] != newParts[1]
+		}
+		changes = append(changes, c)
+	}
+	for name, oldVersion := range before {
+		if _, ok := after[name]; !ok && !renamedFrom[name] {
+			changes = append(changes, DependencyChange{Manifest: manifest, Name: name, Change: depRemoved, OldVersion: oldVersion})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// versionNumbers extracts the leading dotted numbers of a version or version range
+var versionNumbers = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// parseVersion returns the major, minor and patch numbers of a version such as
+// "v1.2.3", "^4.17.21" or ">=2.0"
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	m := versionNumbers.FindStringSubmatch(version)
+	if m == nil {
+		return parts, false
+	}
+	for i := range parts {
+		if m[i+1] != "" {
+			parts[i], _ = strconv.Atoi(m[i+1])
+		}
+	}
+	return parts, true
+}
+
+// compareVersions orders two parsed versions
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseGoMod reads the require directives of a go.mod file
+func parseGoMod(content string) map[string]string {
+	deps := make(map[string]string)
+	inRequire := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inRequire:
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) == 2 {
+			deps[fields[0]] = fields[1]
+		}
+	}
+	return deps
+}
+
+// parsePackageJSON reads the dependency sections of a package.json file
+func parsePackageJSON(content string) map[string]string {
+	var pkg struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	deps := make(map[string]string)
+	if json.Unmarshal([]byte(content), &pkg) != nil {
+		return deps
+	}
+	for _, section := range []map[string]string{pkg.PeerDependencies, pkg.OptionalDependencies, pkg.DevDependencies, pkg.Dependencies} {
+		for name, version := range section {
+			deps[name] = version
+		}
+	}
+	return deps
+}
+
+// requirementLine splits "name[extras]==1.2 ; markers" into the name and version specifier
+var requirementLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*([<>=!~][^;#]*)?`)
+
+// parseRequirements reads a pip requirements file
+func parseRequirements(content string) map[string]string {
+	deps := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if m := requirementLine.FindStringSubmatch(line); m != nil {
+			// Package names are case-insensitive and treat - and _ alike
+			name := strings.ReplaceAll(strings.ToLower(m[1]), "_", "-")
+			deps[name] = strings.ReplaceAll(strings.TrimSpace(m[2]), " ", "")
+		}
+	}
+	return deps
+}
+
+// cargoDependency matches `name = "1.2"` and `name = { version = "1.2", ... }`
+var cargoDependency = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*(?:"([^"]*)"|\{.*?version\s*=\s*"([^"]*)".*\})`)
+
+// parseCargoToml reads the dependency tables of a Cargo.toml file
+func parseCargoToml(content string) map[string]string {
+	deps := make(map[string]string)
+	inDeps := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			table := strings.Trim(line, "[] ")
+			inDeps = table == "dependencies" || table == "dev-dependencies" || table == "build-dependencies" ||
+				strings.HasSuffix(table, ".dependencies")
+			continue
+		}
+		if !inDeps {
+			continue
+		}
+		if m := cargoDependency.FindStringSubmatch(line); m != nil {
+			version := m[2]
+			if version == "" {
+				version = m[3]
+			}
+			deps[m[1]] = version
+		}
+	}
+	return deps
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifests(t *testing.T) {
+	goMod := `module example.com/app
+
+go 1.24
+
+require github.com/single/dep v1.0.0
+
+require (
+	github.com/a/b v1.2.3
+	golang.org/x/sync v0.7.0 // indirect
+)
+
+replace github.com/a/b => ../b
+`
+	want := map[string]string{"github.com/single/dep": "v1.0.0", "github.com/a/b": "v1.2.3", "golang.org/x/sync": "v0.7.0"}
+	if got := parseGoMod(goMod); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGoMod() = %v, want %v", got, want)
+	}
+
+	pkg := `{"name": "app", "dependencies": {"react": "^18.2.0"}, "devDependencies": {"jest": "29.7.0"}}`
+	want = map[string]string{"react": "^18.2.0", "jest": "29.7.0"}
+	if got := parsePackageJSON(pkg); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePackageJSON() = %v, want %v", got, want)
+	}
+	if got := parsePackageJSON("{broken"); len(got) != 0 {
+		t.Errorf("parsePackageJSON(invalid) = %v, want empty", got)
+	}
+
+	requirements := `# web
+Django==4.2.1
+requests[security] >= 2.31 ; python_version > "3.8"
+-r base.txt
+my_pkg
+`
+	want = map[string]string{"django": "==4.2.1", "requests": ">=2.31", "my-pkg": ""}
+	if got := parseRequirements(requirements); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRequirements() = %v, want %v", got, want)
+	}
+
+	cargo := `[package]
+name = "app"
+version = "0.1.0"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+tokio = "1.37"
+
+[dev-dependencies]
+proptest = "1.4"
+`
+	want = map[string]string{"serde": "1.0", "tokio": "1.37", "proptest": "1.4"}
+	if got := parseCargoToml(cargo); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCargoToml() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffDependencies(t *testing.T) {
+	before := map[string]string{
+		"github.com/a/b":       "v1.2.3",
+		"github.com/c/d":       "v1.0.0",
+		"github.com/e/f":       "v0.4.0",
+		"github.com/gone/away": "v1.0.0",
+		"github.com/same/dep":  "v1.1.0",
+		"github.com/down/dep":  "v1.5.0",
+	}
+	after := map[string]string{
+		"github.com/a/b":      "v1.3.0",
+		"github.com/c/d/v2":   "v2.1.0",
+		"github.com/e/f":      "v0.5.0",
+		"github.com/new/dep":  "v0.1.0",
+		"github.com/same/dep": "v1.1.0",
+		"github.com/down/dep": "v1.4.0",
+	}
+
+	var got []string
+	for _, c := range diffDependencies("go.mod", before, after) {
+		got = append(got, c.Describe())
+	}
+	want := []string{
+		"upgraded github.com/a/b v1.2.3 -> v1.3.0",
+		"upgraded github.com/c/d => github.com/c/d/v2 v1.0.0 -> v2.1.0 (major version bump)",
+		"downgraded github.com/down/dep v1.5.0 -> v1.4.0",
+		"upgraded github.com/e/f v0.4.0 -> v0.5.0 (major version bump)",
+		"removed github.com/gone/away v1.0.0",
+		"added github.com/new/dep v0.1.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDependencies() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyzeDependencies(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("web/package.json", `{"dependencies": {"react": "^17.0.2", "left-pad": "1.3.0"}}`)
+	repo.write("go.mod", "module example.com/app\n\nrequire github.com/a/b v1.0.0\n")
+	repo.write("README.md", "app\n")
+	base := repo.commit("initial")
+	repo.write("web/package.json", `{"dependencies": {"react": "^18.2.0"}}`)
+	repo.write("go.mod", "module example.com/app\n\nrequire github.com/a/b v1.0.1\n")
+	repo.write("go.sum", "github.com/a/b v1.0.1 h1:abc=\n")
+	repo.write("README.md", "app docs\n")
+	head := repo.commit("bump deps")
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+	review := AnalyzeDependencies(repo.dir, base, head, files)
+	if review == nil {
+		t.Fatal("AnalyzeDependencies() = nil, want changes")
+	}
+
+	var got []string
+	for _, c := range review.Changes {
+		got = append(got, c.Manifest+": "+c.Describe())
+	}
+	want := []string{
+		"go.mod: upgraded github.com/a/b v1.0.0 -> v1.0.1",
+		"web/package.json: removed left-pad 1.3.0",
+		"web/package.json: upgraded react ^17.0.2 -> ^18.2.0 (major version bump)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(review.Notes) != 1 || !strings.Contains(review.Notes[0], "web/package.json changed dependencies but none of its lockfiles") {
+		t.Errorf("Notes = %q, want a missing lockfile note for package.json only", review.Notes)
+	}
+
+	readmeOnly := []ChangedFile{{Path: "README.md", Status: "M"}}
+	if review := AnalyzeDependencies(repo.dir, base, head, readmeOnly); review != nil {
+		t.Errorf("AnalyzeDependencies() = %+v, want nil without manifest changes", review)
+	}
+}
@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches unified diff hunk headers such as "@@ -12,3 +14,5 @@ func main() {"
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// RenderDiff writes the diff between base and head in the annotated OLD/NEW/CTX
+// format that the prompt asks the model to produce with git and awk
+func RenderDiff(w io.Writer, dir, base, head string) error {
+	if base == "" || head == "" {
+		return fmt.Errorf("merge base and source SHAs are required")
+	}
+	out, err := gitDiff(dir, base, head)
+	if err != nil {
+		return err
+	}
+	return AnnotateDiff(w, strings.NewReader(string(out)))
+}
+
+// gitDiff returns the unified diff between base and head
+func gitDiff(dir, base, head string) ([]byte, error) {
+	out, err := runGit(dir, "diff", "--color=never", "--no-ext-diff", "-M", diffRange(base, head))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff: %w", err)
+	}
+	return out, nil
+}
+
+// AnnotateDiff rewrites a unified diff so every line carries its line number:
+// hunks start with "=== OLD:n NEW:m ===", removed lines are prefixed "OLD:n",
+// added lines "NEW:m" and context lines "CTX:n/m". File headers pass through unchanged.
+func AnnotateDiff(w io.Writer, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	bw := bufio.NewWriter(w)
+
+	inHunk := false
+	oldLine, newLine := 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[3])
+			inHunk = true
+			fmt.Fprintf(bw, "=== OLD:%d NEW:%d ===\n", oldLine, newLine)
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			inHunk = false
+		}
+		if !inHunk {
+			fmt.Fprintln(bw, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintf(bw, "OLD:%d %s\n", oldLine, line)
+			oldLine++
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprintf(bw, "NEW:%d %s\n", newLine, line)
+			newLine++
+		case strings.HasPrefix(line, " "):
+			fmt.Fprintf(bw, "CTX:%d/%d %s\n", oldLine, newLine, line)
+			oldLine++
+			newLine++
+		default:
+			fmt.Fprintln(bw, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read diff: %w", err)
+	}
+	return bw.Flush()
+}
+
+// DiffLine is a single line of a hunk. Kind is '+', '-' or ' '; OldLine is zero
+// for added lines and NewLine is zero for removed lines.
+type DiffLine struct {
+	Kind    byte
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+// Hunk is a contiguous block of changes within a file
+type Hunk struct {
+	OldStart int
+	NewStart int
+	Lines    []DiffLine
+}
+
+// FileDiff holds the parsed hunks of one file. OldPath is set for renames and
+// deletions; Path is the new path, or the old path of a deleted file.
+type FileDiff struct {
+	Path    string
+	OldPath string
+	Hunks   []Hunk
+}
+
+// AddedLines returns the NEW line numbers of the added lines in order
+func (f FileDiff) AddedLines() []int {
+	var lines []int
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == '+' {
+				lines = append(lines, l.NewLine)
+			}
+		}
+	}
+	return lines
+}
+
+// ChangedText returns the added and removed lines without their diff markers
+func (f FileDiff) ChangedText() []string {
+	var text []string
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != ' ' {
+				text = append(text, l.Text)
+			}
+		}
+	}
+	return text
+}
+
+// LoadDiff parses the diff between base and head in dir
+func LoadDiff(dir, base, head string) ([]FileDiff, error) {
+	if base == "" || head == "" {
+		return nil, fmt.Errorf("merge base and source SHAs are required")
+	}
+	out, err := gitDiff(dir, base, head)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDiff(bytes.NewReader(out))
+}
+
+// ParseDiff parses a git unified diff into per-file hunks with line numbers
+func ParseDiff(r io.Reader) ([]FileDiff, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+	oldLine, newLine := 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, FileDiff{})
+			file, hunk = &files[len(files)-1], nil
+			continue
+		}
+		if file == nil {
+			continue
+		}
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[3])
+			file.Hunks = append(file.Hunks, Hunk{OldStart: oldLine, NewStart: newLine})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			continue
+		}
+		if hunk == nil {
+			parseFileHeader(file, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+"):
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: '+', NewLine: newLine, Text: line[1:]})
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: '-', OldLine: oldLine, Text: line[1:]})
+			oldLine++
+		case strings.HasPrefix(line, " "):
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: ' ', OldLine: oldLine, NewLine: newLine, Text: line[1:]})
+			oldLine++
+			newLine++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+
+	for i := range files {
+		if files[i].Path == "" {
+			files[i].Path = files[i].OldPath
+		}
+	}
+	return files, nil
+}
+
+// parseFileHeader records the paths named by the extended header lines of a file diff
+func parseFileHeader(file *FileDiff, line string) {
+	switch {
+	case strings.HasPrefix(line, "--- "):
+		if p := diffPath(line[4:], "a/"); p != "" && p != file.Path {
+			file.OldPath = p
+		}
+	case strings.HasPrefix(line, "+++ "):
+		if p := diffPath(line[4:], "b/"); p != "" {
+			file.Path = p
+			if file.OldPath == p {
+				file.OldPath = ""
+			}
+		}
+	case strings.HasPrefix(line, "rename from "):
+		file.OldPath = diffPath(line[len("rename from "):], "")
+	case strings.HasPrefix(line, "rename to "):
+		file.Path = diffPath(line[len("rename to "):], "")
+	}
+}
+
+// diffPath decodes a path from a diff header, unquoting C-style quoted names and
+// stripping the a/ or b/ prefix. It returns "" for /dev/null.
+func diffPath(s, prefix string) string {
+	s = strings.TrimSuffix(s, "\t")
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		}
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
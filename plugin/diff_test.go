@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotateDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -3,4 +3,5 @@ package main
+ import "fmt"
+-func old() {}
++func new() {}
++func extra() {}
+ func main() {
+\ No newline at end of file
+`
+	expected := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+=== OLD:3 NEW:3 ===
+CTX:3/3  import "fmt"
+OLD:4 -func old() {}
+NEW:4 +func new() {}
+NEW:5 +func extra() {}
+CTX:5/6  func main() {
+\ No newline at end of file
+`
+
+	var out strings.Builder
+	if err := AnnotateDiff(&out, strings.NewReader(diff)); err != nil {
+		t.Fatalf("AnnotateDiff() failed: %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("AnnotateDiff() =\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("app.py", "def a():\n    return 1\n")
+	base := repo.commit("initial")
+	repo.write("app.py", "def a():\n    return 2\n")
+	head := repo.commit("change")
+
+	var out strings.Builder
+	if err := RenderDiff(&out, repo.dir, base, head); err != nil {
+		t.Fatalf("RenderDiff() failed: %v", err)
+	}
+	for _, want := range []string{"=== OLD:1 NEW:1 ===", "OLD:2 -    return 1", "NEW:2 +    return 2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("RenderDiff() output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if err := RenderDiff(&out, repo.dir, "", head); err == nil {
+		t.Error("RenderDiff() should fail without a merge base")
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -3,4 +3,5 @@ package main
+ import "fmt"
+-func old() {}
++func new() {}
++func extra() {}
+ func main() {
+@@ -20,0 +22,1 @@
++// trailing
+diff --git a/old.txt b/new.txt
+similarity 100%
+rename from old.txt
+rename to new.txt
+diff --git a/gone.py b/gone.py
+deleted file mode 100644
+--- a/gone.py
++++ /dev/null
+@@ -1 +0,0 @@
+-print("bye")
+diff --git "a/caf\303\251.txt" "b/caf\303\251.txt"
+new file mode 100644
+--- /dev/null
++++ "b/caf\303\251.txt"
+@@ -0,0 +1 @@
++hi
+`
+
+	files, err := ParseDiff(strings.NewReader(diff))
+	if err != nil {
+		t.Fatalf("ParseDiff() failed: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("ParseDiff() returned %d files, want 4", len(files))
+	}
+
+	main := files[0]
+	if main.Path != "main.go" || main.OldPath != "" || len(main.Hunks) != 2 {
+		t.Errorf("main.go = %+v, want two hunks without an old path", main)
+	}
+	if got := main.AddedLines(); len(got) != 3 || got[0] != 4 || got[1] != 5 || got[2] != 22 {
+		t.Errorf("AddedLines() = %v, want [4 5 22]", got)
+	}
+	if got := main.ChangedText(); len(got) != 4 || got[0] != "func old() {}" {
+		t.Errorf("ChangedText() = %q, want the added and removed lines", got)
+	}
+	if l := main.Hunks[0].Lines[4]; l.Kind != ' ' || l.OldLine != 5 || l.NewLine != 6 {
+		t.Errorf("context line = %+v, want OLD:5 NEW:6", l)
+	}
+
+	if files[1].Path != "new.txt" || files[1].OldPath != "old.txt" || len(files[1].Hunks) != 0 {
+		t.Errorf("rename = %+v, want new.txt renamed from old.txt", files[1])
+	}
+	if files[2].Path != "gone.py" || files[2].Hunks[0].Lines[0].OldLine != 1 {
+		t.Errorf("deletion = %+v, want gone.py with removed line 1", files[2])
+	}
+	if files[3].Path != "café.txt" {
+		t.Errorf("quoted path = %q, want café.txt", files[3].Path)
+	}
+}
+
+func TestLoadDiff(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("app.py", "def a():\n    return 1\n")
+	base := repo.commit("initial")
+	repo.write("app.py", "def a():\n    return 2\n")
+	head := repo.commit("change")
+
+	files, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "app.py" || len(files[0].AddedLines()) != 1 {
+		t.Errorf("LoadDiff() = %+v, want one added line in app.py", files)
+	}
+
+	if _, err := LoadDiff(repo.dir, "", head); err == nil {
+		t.Error("LoadDiff() should fail without a merge base")
+	}
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Markers delimiting the generated settings table in README.md
+const (
+	settingsTableBegin = "<!-- settings-table:begin (generated from plugin/settings.go, do not edit) -->"
+	settingsTableEnd   = "<!-- settings-table:end -->"
+)
+
+// pluginYAMLHeader is the static part of plugin.yml preceding the generated settings
+const pluginYAMLHeader = `# Code generated from plugin/settings.go by "make docs". DO NOT EDIT.
+name: drone-ai-review
+author: Drone Plugins
+description: Generates AI-powered code review prompts from pull request diffs
+tags:
+  - code-review
+  - ai
+  - pull-request
+  - git-diff
+icon: https://raw.githubusercontent.com/drone/brand/master/logos/png/drone-logo-dark_256.png
+
+settings:
+`
+
+// Type returns the documented type of the setting as used in plugin.yml
+func (i SettingInfo) Type() string {
+	switch i.Kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// GeneratePluginYAML renders plugin.yml from the settings metadata
+func GeneratePluginYAML() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(pluginYAMLHeader)
+	for i, info := range SettingsInfo() {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "  %s:\n", info.Name)
+		fmt.Fprintf(&buf, "    type: %s\n", info.Type())
+		fmt.Fprintf(&buf, "    description: %s\n", yamlString(info.Help))
+		if info.Default != "" {
+			fmt.Fprintf(&buf, "    default: %s\n", yamlScalar(info))
+		}
+		if info.Fallback != "" {
+			fmt.Fprintf(&buf, "    default_from_env: %s\n", info.Fallback)
+		}
+		buf.WriteString("    required: false\n")
+	}
+	return buf.Bytes()
+}
+
+// yamlString quotes s when it cannot be written as a plain YAML scalar
+func yamlString(s string) string {
+	if s == "" || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// yamlScalar formats the default of a setting as a YAML scalar of the right type
+func yamlScalar(info SettingInfo) string {
+	if info.Kind == reflect.String {
+		return strconv.Quote(info.Default)
+	}
+	return info.Default
+}
+
+// GenerateSettingsTable renders the README configuration table from the settings metadata
+func GenerateSettingsTable() string {
+	var b strings.Builder
+	b.WriteString("| Parameter | Environment Variable | Type | Default | Description |\n")
+	b.WriteString("|-----------|---------------------|------|---------|-------------|\n")
+	for _, info := range SettingsInfo() {
+		env := "`" + info.Env + "`"
+		if info.Fallback != "" {
+			env += " or `" + info.Fallback + "`"
+		}
+
+		def := "-"
+		switch {
+		case info.Default != "":
+			def = "`" + info.Default + "`"
+		case info.Fallback != "":
+			def = "auto-detected"
+		}
+
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", info.Name, env, info.Type(), def, info.Help)
+	}
+	return b.String()
+}
+
+// UpdateReadmeSettings replaces the generated settings table between its markers in readme
+func UpdateReadmeSettings(readme []byte) ([]byte, error) {
+	text := string(readme)
+	begin := strings.Index(text, settingsTableBegin)
+	end := strings.Index(text, settingsTableEnd)
+	if begin < 0 || end < 0 || end < begin {
+		return nil, fmt.Errorf("README is missing the settings table markers")
+	}
+
+	var b strings.Builder
+	b.WriteString(text[:begin+len(settingsTableBegin)])
+	b.WriteString("\n")
+	b.WriteString(GenerateSettingsTable())
+	b.WriteString(text[end:])
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+// update regenerates the checked-in documentation: go test ./plugin -run TestGeneratedDocs -update
+var update = flag.Bool("update", false, "rewrite plugin.yml and the README settings table")
+
+func TestGeneratedDocs(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatalf("Failed to read README.md: %v", err)
+	}
+	wantReadme, err := UpdateReadmeSettings(readme)
+	if err != nil {
+		t.Fatalf("UpdateReadmeSettings() failed: %v", err)
+	}
+	wantYAML := GeneratePluginYAML()
+
+	if *update {
+		if err := os.WriteFile("../README.md", wantReadme, 0644); err != nil {
+			t.Fatalf("Failed to write README.md: %v", err)
+		}
+		if err := os.WriteFile("../plugin.yml", wantYAML, 0644); err != nil {
+			t.Fatalf("Failed to write plugin.yml: %v", err)
+		}
+		return
+	}
+
+	if string(readme) != string(wantReadme) {
+		t.Error("README.md settings table is stale; run 'make docs'")
+	}
+	gotYAML, err := os.ReadFile("../plugin.yml")
+	if err != nil {
+		t.Fatalf("Failed to read plugin.yml: %v", err)
+	}
+	if string(gotYAML) != string(wantYAML) {
+		t.Error("plugin.yml is stale; run 'make docs'")
+	}
+}
+
+func TestGeneratePluginYAML(t *testing.T) {
+	yaml := string(GeneratePluginYAML())
+
+	for _, info := range SettingsInfo() {
+		if !strings.Contains(yaml, "\n  "+info.Name+":\n") {
+			t.Errorf("plugin.yml should declare setting %s", info.Name)
+		}
+	}
+	for _, want := range []string{
+		"    default_from_env: DRONE_REPO_NAME\n",
+		"  comment_count:\n    type: number\n",
+		"    default: 10\n",
+		"    default: true\n",
+		`    default: "0644"` + "\n",
+	} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("plugin.yml should contain %q", want)
+		}
+	}
+	if strings.Contains(yaml, "output_dir") {
+		t.Error("plugin.yml should not declare settings the code does not read")
+	}
+}
+
+func TestUpdateReadmeSettings(t *testing.T) {
+	readme := "# Title\n\n" + settingsTableBegin + "\nold table\n" + settingsTableEnd + "\n\nFooter\n"
+
+	updated, err := UpdateReadmeSettings([]byte(readme))
+	if err != nil {
+		t.Fatalf("UpdateReadmeSettings() failed: %v", err)
+	}
+	text := string(updated)
+	if strings.Contains(text, "old table") {
+		t.Error("Old table should be replaced")
+	}
+	if !strings.Contains(text, "| `repo_name` | `PLUGIN_REPO_NAME` or `DRONE_REPO_NAME` | string | auto-detected |") {
+		t.Errorf("Generated table is missing the repo_name row:\n%s", text)
+	}
+	if !strings.HasPrefix(text, "# Title\n") || !strings.HasSuffix(text, "Footer\n") {
+		t.Error("Content outside the markers should be preserved")
+	}
+
+	if _, err := UpdateReadmeSettings([]byte("# No markers\n")); err == nil {
+		t.Error("UpdateReadmeSettings() should fail without markers")
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Repository name", "Repository name"},
+		{"Path (default: manifest.json)", `"Path (default: manifest.json)"`},
+		{"- for stdout", `"- for stdout"`},
+		{"Issue#42 reference", "Issue#42 reference"},
+		{"Trailing #comment", `"Trailing #comment"`},
+	}
+
+	for _, tt := range tests {
+		if got := yamlString(tt.input); got != tt.expected {
+			t.Errorf("yamlString(%q) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+)
+
+// settingValue adapts a Settings field to flag.Value
+type settingValue struct {
+	field reflect.Value
+}
+
+func (v *settingValue) String() string {
+	if v == nil || !v.field.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.field.Interface())
+}
+
+func (v *settingValue) Set(s string) error {
+	switch v.field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.field.SetInt(int64(n))
+	default:
+		v.field.SetString(s)
+	}
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be passed as bare flags such as -dry-run
+func (v *settingValue) IsBoolFlag() bool {
+	return v.field.Kind() == reflect.Bool
+}
+
+// BindFlags registers a flag for every setting on fs. Flags write directly into
+// settings, so values already loaded from the environment act as defaults and
+// anything passed on the command line takes precedence.
+func BindFlags(fs *flag.FlagSet, settings *Settings) {
+	v := reflect.ValueOf(settings).Elem()
+	for _, info := range SettingsInfo() {
+		fs.Var(&settingValue{field: v.FieldByName(info.Field)}, info.Flag(), flagUsage(info))
+	}
+}
+
+// flagUsage builds the help text of a setting from its metadata
+func flagUsage(info SettingInfo) string {
+	usage := info.Help + " (env " + info.Env
+	if info.Fallback != "" {
+		usage += ", fallback " + info.Fallback
+	}
+	return usage + ")"
+}
+
+// explicitSettings returns the names of the settings set through the
+// environment or passed as flags to fs
+func explicitSettings(fs *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+	byFlag := make(map[string]string)
+	for _, info := range SettingsInfo() {
+		byFlag[info.Flag()] = info.Name
+		if os.Getenv(info.Env) != "" {
+			explicit[info.Name] = true
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[byFlag[f.Name]] = true
+	})
+	return explicit
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBindFlags(t *testing.T) {
+	settings := Settings{CommentCount: 10, EnableBugs: true, OutputFile: "../output/task.txt"}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	BindFlags(fs, &settings)
+
+	err := fs.Parse([]string{"-comment-count", "25", "-enable-bugs=false", "-dry-run", "-output-file", "-"})
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if settings.CommentCount != 25 {
+		t.Errorf("CommentCount = %v, want 25", settings.CommentCount)
+	}
+	if settings.EnableBugs {
+		t.Error("EnableBugs should be false")
+	}
+	if !settings.DryRun {
+		t.Error("DryRun should be set by a bare boolean flag")
+	}
+	if settings.OutputFile != StdoutPath {
+		t.Errorf("OutputFile = %v, want %v", settings.OutputFile, StdoutPath)
+	}
+
+	if err := fs.Parse([]string{"-comment-count", "many"}); err == nil {
+		t.Error("Parse() should reject a non-integer comment count")
+	}
+}
+
+func TestBindFlagsCoversEverySetting(t *testing.T) {
+	var settings Settings
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlags(fs, &settings)
+
+	for _, info := range SettingsInfo() {
+		f := fs.Lookup(info.Flag())
+		if f == nil {
+			t.Errorf("No flag registered for setting %s", info.Name)
+			continue
+		}
+		if !strings.Contains(f.Usage, info.Env) {
+			t.Errorf("Usage of -%s should mention %s, got %q", info.Flag(), info.Env, f.Usage)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxHeaderScan bounds how far above a hunk the heuristics look for a definition
+const maxHeaderScan = 1000
+
+// EnclosingFunction is a function, method or class of the new file that
+// contains changed lines, with its source
+type EnclosingFunction struct {
+	Header    string   `json:"header"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Changes   []int    `json:"changes"`
+	Lines     []string `json:"-"`
+}
+
+// ChangeList formats the first NEW line of each changed range inside the function
+func (f EnclosingFunction) ChangeList() string {
+	parts := make([]string, len(f.Changes))
+	for i, l := range f.Changes {
+		parts[i] = fmt.Sprint(l)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Numbered returns the source of the function with its NEW line numbers
+func (f EnclosingFunction) Numbered() string {
+	width := len(fmt.Sprint(f.EndLine))
+	var b strings.Builder
+	for i, line := range f.Lines {
+		fmt.Fprintf(&b, "%*d | %s\n", width, f.StartLine+i, line)
+	}
+	return b.String()
+}
+
+// FunctionContext lists the enclosing functions of the hunks of one file
+type FunctionContext struct {
+	Path      string              `json:"path"`
+	Functions []EnclosingFunction `json:"functions"`
+	Omitted   int                 `json:"omitted,omitempty"`
+}
+
+// span is an inclusive range of lines
+type span struct {
+	start, end int
+}
+
+// braceLanguages are the extensions whose blocks are delimited by braces
+var braceLanguages = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+	".java": true, ".kt": true, ".kts": true, ".scala": true, ".groovy": true,
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".cxx": true, ".hpp": true, ".cs": true,
+	".rs": true, ".swift": true, ".php": true, ".dart": true,
+}
+
+// ExtractFunctionContext finds the enclosing function of every hunk of the
+// modified files and reads it from the file at sha. Each file embeds at most
+// maxLines lines of functions, or any number when maxLines is 0; added files are
+// skipped since the diff already shows them in full.
+func ExtractFunctionContext(dir, sha string, files []ChangedFile, diffs []FileDiff, maxLines int) []FunctionContext {
+	status := make(map[string]ChangedFile)
+	for _, f := range files {
+		status[f.Path] = f
+	}
+
+	var result []FunctionContext
+	for _, d := range diffs {
+		f, ok := status[d.Path]
+		if !ok || f.Status == "A" || f.Status == "D" || f.Binary || len(d.Hunks) == 0 {
+			continue
+		}
+		content := fileAt(dir, sha, d.Path)
+		if content == "" {
+			continue
+		}
+		if fc := enclosingFunctions(d, content, maxLines); fc != nil {
+			result = append(result, *fc)
+		}
+	}
+	return result
+}
+
+// enclosingFunctions collects the enclosing functions of the hunks of one file
+func enclosingFunctions(d FileDiff, content string, maxLines int) *FunctionContext {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	find := functionFinder(d.Path, content, lines)
+	if find == nil {
+		return nil
+	}
+
+	added := make(map[int]bool)
+	for _, l := range d.AddedLines() {
+		added[l] = true
+	}
+	functions := make(map[span]*EnclosingFunction)
+	for _, h := range d.Hunks {
+		for _, changed := range changedRanges(h) {
+			s, found := find(changed.start)
+			if !found {
+				if s, found = find(changed.end); !found {
+					continue
+				}
+			}
+			if s.end > len(lines) || allAdded(s, added) {
+				continue
+			}
+			if f, ok := functions[s]; ok {
+				f.Changes = append(f.Changes, changed.start)
+				continue
+			}
+			functions[s] = &EnclosingFunction{
+				Header:    functionHeader(lines[s.start-1]),
+				StartLine: s.start,
+				EndLine:   s.end,
+				Changes:   []int{changed.start},
+				Lines:     lines[s.start-1 : s.end],
+			}
+		}
+	}
+	if len(functions) == 0 {
+		return nil
+	}
+
+	sorted := make([]*EnclosingFunction, 0, len(functions))
+	for _, f := range functions {
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartLine < sorted[j].StartLine })
+
+	fc := &FunctionContext{Path: d.Path}
+	total := 0
+	for _, f := range sorted {
+		if maxLines > 0 && total+len(f.Lines) > maxLines {
+			fc.Omitted++
+			continue
+		}
+		total += len(f.Lines)
+		fc.Functions = append(fc.Functions, *f)
+	}
+	return fc
+}
+
+// changedRanges returns the NEW lines spanned by each run of consecutive
+// changes in a hunk. A removal is placed at the line that follows it in the new file.
+func changedRanges(h Hunk) []span {
+	var ranges []span
+	next := h.NewStart
+	inRun := false
+	for _, l := range h.Lines {
+		line := 0
+		switch l.Kind {
+		case '+':
+			line = l.NewLine
+			next = l.NewLine + 1
+		case '-':
+			line = next
+		default:
+			next = l.NewLine + 1
+			inRun = false
+			continue
+		}
+		if !inRun {
+			ranges = append(ranges, span{line, line})
+			inRun = true
+		}
+		ranges[len(ranges)-1].end = line
+	}
+	return ranges
+}
+
+// allAdded reports whether every line of s was added, in which case the diff already shows it
+func allAdded(s span, added map[int]bool) bool {
+	for l := s.start; l <= s.end; l++ {
+		if !added[l] {
+			return false
+		}
+	}
+	return true
+}
+
+// functionHeader shortens the first line of a definition for the prompt
+func functionHeader(line string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{"))
+}
+
+// functionFinder returns the lookup of the innermost function enclosing a line
+// for the language of p, or nil when the language is not supported
+func functionFinder(p, content string, lines []string) func(line int) (span, bool) {
+	ext := strings.ToLower(path.Ext(p))
+	switch {
+	case ext == ".go":
+		if spans, ok := goFunctionSpans(content); ok {
+			return func(line int) (span, bool) { return innermostSpan(spans, line) }
+		}
+		return func(line int) (span, bool) { return braceSpan(lines, line) }
+	case ext == ".py":
+		return func(line int) (span, bool) { return indentSpan(lines, line) }
+	case braceLanguages[ext]:
+		return func(line int) (span, bool) { return braceSpan(lines, line) }
+	}
+	return nil
+}
+
+// goFunctionSpans returns the line ranges of the function and method
+// declarations of a Go file
+func goFunctionSpans(content string) ([]span, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+	var spans []span
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			spans = append(spans, span{fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line})
+		}
+	}
+	return spans, true
+}
+
+// innermostSpan returns the shortest span containing line
+func innermostSpan(spans []span, line int) (span, bool) {
+	best, found := span{}, false
+	for _, s := range spans {
+		if s.start <= line && line <= s.end && (!found || s.end-s.start < best.end-best.start) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+var (
+	// braceDefinition matches lines opening a function, method or class body
+	braceDefinition = regexp.MustCompile(`\b(class|interface|struct|enum|trait|impl|object|namespace|record)\b[^;=]*\{\s*$|\)[^;{}]*\{\s*$|=>\s*\{\s*$`)
+	// braceSignature matches the first line of a definition whose brace is on the next line
+	braceSignature = regexp.MustCompile(`\)[^;{}]*$`)
+	// controlStatement matches blocks that are not definitions
+	controlStatement = regexp.MustCompile(`^\s*(\}\s*)?(if|else|for|foreach|while|do|switch|case|catch|try|finally|return|using|lock|synchronized|with)\b`)
+	// quotedText matches string and character literals, whose braces do not count
+	quotedText = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`")
+)
+
+// braceSpan walks up from line to the nearest definition whose braces enclose it
+func braceSpan(lines []string, line int) (span, bool) {
+	for i := min(line, len(lines)); i >= 1 && i > line-maxHeaderScan; i-- {
+		text := lines[i-1]
+		if controlStatement.MatchString(text) {
+			continue
+		}
+		open := i
+		switch {
+		case braceDefinition.MatchString(text):
+		case braceSignature.MatchString(text) && i < len(lines) && strings.TrimSpace(lines[i]) == "{":
+			open = i + 1
+		default:
+			continue
+		}
+		if end, ok := closingBrace(lines, open); ok && end >= line {
+			return span{i, end}, true
+		}
+	}
+	return span{}, false
+}
+
+// closingBrace returns the line that closes the block opened on line open
+func closingBrace(lines []string, open int) (int, bool) {
+	depth := 0
+	for i := open; i <= len(lines); i++ {
+		text := quotedText.ReplaceAllString(lines[i-1], "")
+		if c := strings.Index(text, "//"); c >= 0 {
+			text = text[:c]
+		}
+		for _, r := range text {
+			switch r {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return i, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// indentDefinition matches Python function and class definitions
+var indentDefinition = regexp.MustCompile(`^\s*(async\s+def|def|class)\s+\w+`)
+
+// indentSpan walks up from line to the nearest less indented definition
+func indentSpan(lines []string, line int) (span, bool) {
+	limit := math.MaxInt
+	for i := min(line, len(lines)); i >= 1 && i > line-maxHeaderScan; i-- {
+		text := lines[i-1]
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		indent := indentation(text)
+		if i != line && indent >= limit {
+			continue
+		}
+		if indentDefinition.MatchString(text) {
+			if end := indentEnd(lines, i); end >= line {
+				return span{i, end}, true
+			}
+		}
+		if indent == 0 && i != line {
+			break
+		}
+		limit = indent
+	}
+	return span{}, false
+}
+
+// indentEnd returns the last line of the body of the definition starting at line start
+func indentEnd(lines []string, start int) int {
+	indent := indentation(lines[start-1])
+	// The signature may continue on lines that are not more indented
+	body := start
+	for body < len(lines) && !strings.HasSuffix(strings.TrimSpace(stripComment(lines[body-1])), ":") {
+		body++
+	}
+	end := body
+	for i := body + 1; i <= len(lines); i++ {
+		if strings.TrimSpace(lines[i-1]) == "" {
+			continue
+		}
+		if indentation(lines[i-1]) <= indent {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// stripComment removes a trailing # comment from a Python line
+func stripComment(line string) string {
+	if c := strings.Index(line, "#"); c >= 0 {
+		return line[:c]
+	}
+	return line
+}
+
+// indentation counts the leading whitespace of a line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
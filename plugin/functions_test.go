@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangedRanges(t *testing.T) {
+	h := Hunk{NewStart: 10, Lines: []DiffLine{
+		{Kind: ' ', OldLine: 10, NewLine: 10},
+		{Kind: '-', OldLine: 11},
+		{Kind: '+', NewLine: 11},
+		{Kind: '+', NewLine: 12},
+		{Kind: ' ', OldLine: 12, NewLine: 13},
+		{Kind: '-', OldLine: 13},
+		{Kind: ' ', OldLine: 14, NewLine: 14},
+	}}
+	want := []span{{11, 12}, {14, 14}}
+	if got := changedRanges(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedRanges() = %v, want %v", got, want)
+	}
+
+	if got := changedRanges(Hunk{NewStart: 1, Lines: []DiffLine{{Kind: ' ', OldLine: 1, NewLine: 1}}}); got != nil {
+		t.Errorf("changedRanges() = %v, want nil for a hunk without changes", got)
+	}
+}
+
+func TestBraceSpan(t *testing.T) {
+	lines := strings.Split(`class Cart {
+  total() {
+    let sum = 0;
+    for (const item of this.items) {
+      sum += item.price; // }
+    }
+    return sum;
+  }
+
+  describe(name) {
+    return "{" + name;
+  }
+}
+
+public void Save(Order order)
+{
+    if (order == null)
+    {
+        return;
+    }
+}`, "\n")
+
+	tests := []struct {
+		line int
+		want span
+		ok   bool
+	}{
+		{line: 5, want: span{2, 8}, ok: true},
+		{line: 11, want: span{10, 12}, ok: true},
+		{line: 9, want: span{1, 13}, ok: true},
+		{line: 19, want: span{15, 21}, ok: true},
+		{line: 14, ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := braceSpan(lines, tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("braceSpan(%d) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIndentSpan(t *testing.T) {
+	lines := strings.Split(`import os
+
+
+class Loader:
+    def load(self, path):
+        if path:
+            return open(path)
+
+        return None
+
+    async def fetch(
+        self,
+        url,
+    ) -> bytes:  # remote
+        return b""
+
+
+def main():
+    Loader().load("x")
+`, "\n")
+
+	tests := []struct {
+		line int
+		want span
+		ok   bool
+	}{
+		{line: 7, want: span{5, 9}, ok: true},
+		{line: 9, want: span{5, 9}, ok: true},
+		{line: 13, want: span{11, 15}, ok: true},
+		{line: 10, want: span{4, 15}, ok: true},
+		{line: 19, want: span{18, 19}, ok: true},
+		{line: 1, ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := indentSpan(lines, tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("indentSpan(%d) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtractFunctionContext(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("calc.go", `package calc
+
+// Add sums two numbers
+func Add(a, b int) int {
+	return a + b
+}
+
+func Div(a, b int) int {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+`)
+	repo.write("notes.txt", "one\n")
+	base := repo.commit("initial")
+
+	repo.write("calc.go", `package calc
+
+// Add sums two numbers
+func Add(a, b int) int {
+	return a + b + 0
+}
+
+func Div(a, b int) int {
+	if b == 0 {
+		panic("division by zero")
+	}
+	return a / b
+}
+
+func Mul(a, b int) int {
+	return a * b
+}
+`)
+	repo.write("notes.txt", "two\n")
+	repo.write("new.go", "package calc\n\nfunc New() {}\n")
+	head := repo.commit("change")
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+	diffs, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+
+	got := ExtractFunctionContext(repo.dir, head, files, diffs, 0)
+	want := []FunctionContext{{
+		Path: "calc.go",
+		Functions: []EnclosingFunction{
+			{Header: "func Add(a, b int) int", StartLine: 4, EndLine: 6, Changes: []int{5}, Lines: []string{"func Add(a, b int) int {", "\treturn a + b + 0", "}"}},
+			{Header: "func Div(a, b int) int", StartLine: 8, EndLine: 13, Changes: []int{10}, Lines: []string{"func Div(a, b int) int {", "\tif b == 0 {", "\t\tpanic(\"division by zero\")", "\t}", "\treturn a / b", "}"}},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractFunctionContext() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := want[0].Functions[0].Numbered(); got != "4 | func Add(a, b int) int {\n5 | \treturn a + b + 0\n6 | }\n" {
+		t.Errorf("Numbered() = %q", got)
+	}
+
+	limited := ExtractFunctionContext(repo.dir, head, files, diffs, 5)
+	if len(limited) != 1 || len(limited[0].Functions) != 1 || limited[0].Omitted != 1 {
+		t.Errorf("ExtractFunctionContext() with a limit = %+v, want one function and one omitted", limited)
+	}
+}
@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ChangedFile describes a single file touched between the merge base and the source commit
+type ChangedFile struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// DiffStats summarizes the size of the change set
+type DiffStats struct {
+	Files     int `json:"files"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+// runGit executes a git command in dir and returns its standard output
+func runGit(dir string, args ...string) ([]byte, error) {
+	return runGitInput(dir, nil, args...)
+}
+
+// runGitInput executes a git command in dir with input on its standard input
+// and returns its standard output
+func runGitInput(dir string, input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return out, nil
+}
+
+// diffRange returns the three-dot revision range used throughout the prompt
+func diffRange(base, head string) string {
+	return base + "..." + head
+}
+
+// ListChangedFiles returns the files changed between base and head with their line counts
+func ListChangedFiles(dir, base, head string) ([]ChangedFile, error) {
+	if base == "" || head == "" {
+		return nil, fmt.Errorf("merge base and source SHAs are required")
+	}
+
+	statusOut, err := runGit(dir, "diff", "--no-color", "--no-ext-diff", "-M", "--name-status", "-z", diffRange(base, head))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+	numstatOut, err := runGit(dir, "diff", "--no-color", "--no-ext-diff", "-M", "--numstat", "-z", diffRange(base, head))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count changed lines: %w", err)
+	}
+
+	files, err := parseNameStatus(statusOut)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := parseNumstat(numstatOut)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		if c, ok := counts[files[i].Path]; ok {
+			files[i].Additions = c.Additions
+			files[i].Deletions = c.Deletions
+			files[i].Binary = c.Binary
+		}
+	}
+	return files, nil
+}
+
+// parseNameStatus parses the output of git diff --name-status -z
+func parseNameStatus(out []byte) ([]ChangedFile, error) {
+	fields := splitNul(out)
+	var files []ChangedFile
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		i++
+		if status == "" {
+			continue
+		}
+		// Renames and copies carry a similarity score and two paths
+		code := status[:1]
+		if code == "R" || code == "C" {
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("malformed name-status entry %q", status)
+			}
+			files = append(files, ChangedFile{OldPath: fields[i], Path: fields[i+1], Status: code})
+			i += 2
+			continue
+		}
+		if i >= len(fields) {
+			return nil, fmt.Errorf("malformed name-status entry %q", status)
+		}
+		files = append(files, ChangedFile{Path: fields[i], Status: code})
+		i++
+	}
+	return files, nil
+}
+
+// parseNumstat parses the output of git diff --numstat -z keyed by the new path
+func parseNumstat(out []byte) (map[string]ChangedFile, error) {
+	fields := splitNul(out)
+	counts := make(map[string]ChangedFile)
+	for i := 0; i < len(fields); {
+		entry := fields[i]
+		i++
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "\t", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed numstat entry %q", entry)
+		}
+
+		file := ChangedFile{Path: parts[2]}
+		// An empty path means a rename: the old and new paths follow as separate fields
+		if file.Path == "" {
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("malformed numstat rename entry %q", entry)
+			}
+			file.OldPath, file.Path = fields[i], fields[i+1]
+			i += 2
+		}
+
+		if parts[0] == "-" && parts[1] == "-" {
+			file.Binary = true
+		} else {
+			var err error
+			if file.Additions, err = strconv.Atoi(parts[0]); err != nil {
+				return nil, fmt.Errorf("malformed numstat entry %q: %w", entry, err)
+			}
+			if file.Deletions, err = strconv.Atoi(parts[1]); err != nil {
+				return nil, fmt.Errorf("malformed numstat entry %q: %w", entry, err)
+			}
+		}
+		counts[file.Path] = file
+	}
+	return counts, nil
+}
+
+// splitNul splits NUL-terminated git output into fields
+func splitNul(out []byte) []string {
+	s := strings.TrimSuffix(string(out), "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// SummarizeDiff totals the line counts of the changed files
+func SummarizeDiff(files []ChangedFile) DiffStats {
+	stats := DiffStats{Files: len(files)}
+	for _, f := range files {
+		stats.Additions += f.Additions
+		stats.Deletions += f.Deletions
+	}
+	return stats
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPath preserves PATH across tests that clear the environment
+var testPath = os.Getenv("PATH")
+
+// testRepo is a throwaway git repository for exercising diff helpers
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+// newTestRepo initializes an empty repository in a temporary directory
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	t.Setenv("PATH", testPath)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	repo := &testRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "-q", "-b", "main")
+	repo.git("config", "user.name", "Test User")
+	repo.git("config", "user.email", "test@example.com")
+	repo.git("config", "commit.gpgsign", "false")
+	return repo
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	out, err := runGit(r.dir, args...)
+	if err != nil {
+		r.t.Fatalf("git %v failed: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// write creates or replaces a file in the working tree
+func (r *testRepo) write(name, content string) {
+	r.t.Helper()
+	path := filepath.Join(r.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		r.t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		r.t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+// commit stages everything and returns the new commit SHA
+func (r *testRepo) commit(message string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "--allow-empty", "-m", message)
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestListChangedFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n\nfunc main() {}\n")
+	repo.write("old.txt", "one\ntwo\nthree\nfour\nfive\n")
+	repo.write("remove.txt", "gone\n")
+	base := repo.commit("initial")
+
+	repo.write("main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	repo.git("mv", "old.txt", "renamed.txt")
+	repo.git("rm", "-q", "remove.txt")
+	repo.write("image.bin", "\x00\x01\x02")
+	head := repo.commit("change")
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+
+	byPath := make(map[string]ChangedFile)
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+	if len(byPath) != 4 {
+		t.Fatalf("got %d files, want 4: %+v", len(files), files)
+	}
+
+	if f := byPath["main.go"]; f.Status != "M" || f.Additions != 5 || f.Deletions != 1 {
+		t.Errorf("main.go = %+v, want M +5 -1", f)
+	}
+	if f := byPath["renamed.txt"]; f.Status != "R" || f.OldPath != "old.txt" {
+		t.Errorf("renamed.txt = %+v, want rename from old.txt", f)
+	}
+	if f := byPath["remove.txt"]; f.Status != "D" || f.Deletions != 1 {
+		t.Errorf("remove.txt = %+v, want D -1", f)
+	}
+	if f := byPath["image.bin"]; f.Status != "A" || !f.Binary {
+		t.Errorf("image.bin = %+v, want binary addition", f)
+	}
+
+	stats := SummarizeDiff(files)
+	if stats.Files != 4 || stats.Additions != 5 || stats.Deletions != 2 {
+		t.Errorf("SummarizeDiff() = %+v, want 4 files +5 -2", stats)
+	}
+}
+
+func TestListChangedFilesErrors(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("initial")
+
+	if _, err := ListChangedFiles(repo.dir, "", "HEAD"); err == nil {
+		t.Error("ListChangedFiles() should fail without a merge base")
+	}
+	if _, err := ListChangedFiles(repo.dir, "abc123", "def456"); err == nil {
+		t.Error("ListChangedFiles() should fail for unknown revisions")
+	}
+}
@@ -0,0 +1,348 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Language describes how to recognize a language and what to check in its code
+type Language struct {
+	ID           string
+	Name         string
+	Extensions   []string
+	Filenames    []string
+	Interpreters []string
+	Checks       []string
+}
+
+// LanguageGuidance is the checklist rendered into the prompt for a language present in the diff
+type LanguageGuidance struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Files  []string `json:"files"`
+	Checks []string `json:"checks"`
+}
+
+// languages is the curated set of built-in per-language checklists.
+// Teams can replace a checklist with <language_rules_path>/<id>.md.
+var languages = []Language{
+	{
+		ID:         "go",
+		Name:       "Go",
+		Extensions: []string{".go"},
+		Checks: []string{
+			"Unchecked or silently discarded errors, and errors wrapped without %w",
+			"Goroutine leaks: goroutines blocked forever on channels or missing context cancellation",
+			"defer inside loops holding resources until the function returns",
+			"Data races on shared maps, slices or struct fields accessed without synchronization",
+			"Loop variables or pointers to them captured by closures and goroutines",
+			"Nil map writes, nil pointer dereferences and unchecked type assertions",
+		},
+	},
+	{
+		ID:         "typescript",
+		Name:       "TypeScript",
+		Extensions: []string{".ts", ".tsx", ".mts", ".cts"},
+		Checks: []string{
+			"Unhandled promise rejections and missing await on async calls",
+			"Use of any, non-null assertions (!) or type casts that hide real type errors",
+			"Missing null/undefined checks on optional values",
+			"React hooks with missing or incorrect dependency arrays",
+		},
+	},
+	{
+		ID:           "javascript",
+		Name:         "JavaScript",
+		Extensions:   []string{".js", ".jsx", ".mjs", ".cjs"},
+		Interpreters: []string{"node", "nodejs", "deno", "bun"},
+		Checks: []string{
+			"Unhandled promise rejections and missing await on async calls",
+			"Loose equality (==) and implicit type coercion bugs",
+			"Accidental globals and this binding errors in callbacks",
+			"Unsanitized input reaching innerHTML, eval or child_process",
+		},
+	},
+	{
+		ID:           "python",
+		Name:         "Python",
+		Extensions:   []string{".py", ".pyi"},
+		Interpreters: []string{"python", "python2", "python3"},
+		Checks: []string{
+			"Mutable default arguments shared between calls",
+			"Bare or overly broad except clauses that swallow errors",
+			"Resources opened without a context manager",
+			"Blocking calls inside async functions",
+			"SQL or shell commands built with string formatting",
+		},
+	},
+	{
+		ID:         "sql",
+		Name:       "SQL",
+		Extensions: []string{".sql"},
+		Checks: []string{
+			"Queries filtering or joining on columns without a supporting index",
+			"Unbounded queries without LIMIT or pagination on large tables",
+			"UPDATE or DELETE statements without a restrictive WHERE clause",
+			"Locking or long-running statements on hot tables",
+		},
+	},
+	{
+		ID:         "terraform",
+		Name:       "Terraform",
+		Extensions: []string{".tf", ".tfvars"},
+		Checks: []string{
+			"Changes that force resource replacement or destroy stateful resources",
+			"Overly permissive IAM policies, security groups or public access",
+			"Hardcoded secrets or credentials",
+			"Provider and module versions that are not pinned",
+		},
+	},
+	{
+		ID:         "java",
+		Name:       "Java",
+		Extensions: []string{".java"},
+		Checks: []string{
+			"Resources not closed with try-with-resources",
+			"Null dereferences of values returned from maps, optionals or external calls",
+			"Shared mutable state accessed from multiple threads without synchronization",
+			"Catching and ignoring exceptions",
+		},
+	},
+	{
+		ID:           "shell",
+		Name:         "Shell",
+		Extensions:   []string{".sh", ".bash", ".zsh"},
+		Interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"},
+		Checks: []string{
+			"Unquoted variable expansions subject to word splitting and globbing",
+			"Missing set -euo pipefail or unchecked command failures",
+			"Unsafe use of eval or untrusted input in commands",
+		},
+	},
+	{
+		ID:           "ruby",
+		Name:         "Ruby",
+		Extensions:   []string{".rb", ".rake"},
+		Filenames:    []string{"Gemfile", "Rakefile"},
+		Interpreters: []string{"ruby"},
+		Checks: []string{
+			"N+1 queries in ActiveRecord associations",
+			"Mass assignment of unpermitted parameters",
+			"nil receivers from finders that can return nil",
+		},
+	},
+	{
+		ID:         "rust",
+		Name:       "Rust",
+		Extensions: []string{".rs"},
+		Checks: []string{
+			"unwrap() or expect() on values that can fail at runtime",
+			"unsafe blocks without a documented safety invariant",
+			"Blocking calls inside async code",
+		},
+	},
+	{
+		ID:        "dockerfile",
+		Name:      "Dockerfile",
+		Filenames: []string{"Dockerfile", "Containerfile"},
+		Checks: []string{
+			"Base images that are not pinned to a version or digest",
+			"Containers running as root without need",
+			"Secrets copied into image layers",
+		},
+	},
+}
+
+// findLanguage looks up a built-in language by ID or display name, case-insensitively
+func findLanguage(name string) *Language {
+	for i := range languages {
+		if strings.EqualFold(languages[i].ID, name) || strings.EqualFold(languages[i].Name, name) {
+			return &languages[i]
+		}
+	}
+	return nil
+}
+
+// DetectLanguage identifies the language of a file from its name and, failing that,
+// from the shebang line of its content. It returns nil for unrecognized files.
+func DetectLanguage(filePath string, content []byte) *Language {
+	base := path.Base(filePath)
+	ext := strings.ToLower(path.Ext(base))
+	for i := range languages {
+		for _, name := range languages[i].Filenames {
+			if base == name || strings.HasPrefix(base, name+".") {
+				return &languages[i]
+			}
+		}
+		for _, e := range languages[i].Extensions {
+			if ext == e {
+				return &languages[i]
+			}
+		}
+	}
+
+	if interpreter := shebangInterpreter(content); interpreter != "" {
+		for i := range languages {
+			for _, name := range languages[i].Interpreters {
+				if interpreter == name {
+					return &languages[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shebangInterpreter extracts the interpreter name from a "#!" line, following /usr/bin/env
+func shebangInterpreter(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = path.Base(f)
+				break
+			}
+		}
+	}
+	return interpreter
+}
+
+// DetectLanguages groups the changed files by language and returns the checklist for
+// each language present. Deleted files are skipped since there is no new code to review.
+func DetectLanguages(dir, sha string, files []ChangedFile, rulesDir string) []LanguageGuidance {
+	var paths []string
+	for _, f := range files {
+		if f.Status != "D" && !f.Binary {
+			paths = append(paths, f.Path)
+		}
+	}
+	overrides := gitattributesLanguages(dir, paths)
+
+	byID := make(map[string]*LanguageGuidance)
+	var order []string
+	for _, p := range paths {
+		lang := findLanguage(overrides[p])
+		if lang == nil {
+			lang = DetectLanguage(p, nil)
+		}
+		if lang == nil && path.Ext(p) == "" {
+			lang = DetectLanguage(p, fileHead(dir, sha, p))
+		}
+		if lang == nil {
+			continue
+		}
+
+		g, ok := byID[lang.ID]
+		if !ok {
+			g = &LanguageGuidance{ID: lang.ID, Name: lang.Name, Checks: languageChecks(rulesDir, *lang)}
+			byID[lang.ID] = g
+			order = append(order, lang.ID)
+		}
+		g.Files = append(g.Files, p)
+	}
+
+	sort.Strings(order)
+	var result []LanguageGuidance
+	for _, id := range order {
+		result = append(result, *byID[id])
+	}
+	return result
+}
+
+// fileHeadSize is the number of bytes read from the start of a file to find its shebang line
+const fileHeadSize = 256
+
+// gitattributesLanguages returns linguist-language overrides from .gitattributes
+// keyed by path. Paths are passed on standard input so any number of them fits.
+func gitattributesLanguages(dir string, paths []string) map[string]string {
+	result := make(map[string]string)
+	if len(paths) == 0 {
+		return result
+	}
+
+	input := []byte(strings.Join(paths, "\x00") + "\x00")
+	out, err := runGitInput(dir, input, "check-attr", "--stdin", "-z", "linguist-language")
+	if err != nil {
+		return result
+	}
+
+	// Output is a sequence of path, attribute, value triples
+	fields := splitNul(out)
+	for i := 0; i+2 < len(fields); i += 3 {
+		value := fields[i+2]
+		if value != "unspecified" && value != "unset" && value != "set" {
+			result[fields[i]] = value
+		}
+	}
+	return result
+}
+
+// fileHead returns the first bytes of a file at sha, enough to read a shebang
+// line, without reading the rest of the blob
+func fileHead(dir, sha, filePath string) []byte {
+	if sha == "" {
+		return nil
+	}
+	cmd := exec.Command("git", "cat-file", "blob", sha+":"+filePath)
+	cmd.Dir = dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(stdout, fileHeadSize))
+	// Stop git rather than let it write the rest of a large blob
+	cmd.Process.Kill()
+	cmd.Wait()
+	if err != nil || len(head) == 0 {
+		return nil
+	}
+	return head
+}
+
+// languageChecks returns the checklist for lang, preferring a team override file
+// <rulesDir>/<id>.md whose list items replace the built-in checks
+func languageChecks(rulesDir string, lang Language) []string {
+	if rulesDir == "" {
+		return lang.Checks
+	}
+	file, err := os.Open(filepath.Join(rulesDir, lang.ID+".md"))
+	if err != nil {
+		return lang.Checks
+	}
+	defer file.Close()
+
+	var checks []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			checks = append(checks, strings.TrimSpace(item))
+		} else if item, ok := strings.CutPrefix(line, "* "); ok {
+			checks = append(checks, strings.TrimSpace(item))
+		}
+	}
+	if len(checks) == 0 {
+		return lang.Checks
+	}
+	return checks
+}
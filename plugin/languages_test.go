@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected string
+	}{
+		{"go extension", "plugin/writer.go", "", "go"},
+		{"typescript extension", "web/src/app.tsx", "", "typescript"},
+		{"uppercase extension", "db/QUERY.SQL", "", "sql"},
+		{"terraform", "infra/main.tf", "", "terraform"},
+		{"dockerfile by name", "Dockerfile", "", "dockerfile"},
+		{"dockerfile variant", "build/Dockerfile.dev", "", "dockerfile"},
+		{"shebang via env", "scripts/deploy", "#!/usr/bin/env python3\nprint(1)\n", "python"},
+		{"shebang with flags", "bin/run", "#!/usr/bin/env -S node --harmony\n", "javascript"},
+		{"direct shebang", "bin/setup", "#!/bin/bash\nset -e\n", "shell"},
+		{"unknown", "README.md", "", ""},
+		{"no extension without shebang", "LICENSE", "MIT License", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang := DetectLanguage(tt.path, []byte(tt.content))
+			got := ""
+			if lang != nil {
+				got = lang.ID
+			}
+			if got != tt.expected {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("README.md", "# readme\n")
+	base := repo.commit("initial")
+
+	repo.write("main.go", "package main\n")
+	repo.write("util.go", "package main\n")
+	repo.write("scripts/release", "#!/bin/sh\necho release\n")
+	repo.write("queries/report.txt", "SELECT 1;\n")
+	repo.write(".gitattributes", "queries/*.txt linguist-language=SQL\n")
+	repo.write("README.md", "# changed\n")
+	head := repo.commit("change")
+
+	rulesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rulesDir, "sql.md"), []byte("# SQL rules\n- Every query must use a tenant filter\n* Avoid SELECT *\n"), 0644); err != nil {
+		t.Fatalf("Failed to write override: %v", err)
+	}
+
+	files, err := ListChangedFiles(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListChangedFiles() failed: %v", err)
+	}
+	guidance := DetectLanguages(repo.dir, head, files, rulesDir)
+
+	byID := make(map[string]LanguageGuidance)
+	for _, g := range guidance {
+		byID[g.ID] = g
+	}
+	if len(byID) != 3 {
+		t.Fatalf("got languages %+v, want go, shell and sql", guidance)
+	}
+	if !reflect.DeepEqual(byID["go"].Files, []string{"main.go", "util.go"}) {
+		t.Errorf("Go files = %v, want main.go and util.go", byID["go"].Files)
+	}
+	if !reflect.DeepEqual(byID["shell"].Files, []string{"scripts/release"}) {
+		t.Errorf("Shell files = %v, want scripts/release detected by shebang", byID["shell"].Files)
+	}
+	if !reflect.DeepEqual(byID["sql"].Checks, []string{"Every query must use a tenant filter", "Avoid SELECT *"}) {
+		t.Errorf("SQL checks = %v, want the override from sql.md", byID["sql"].Checks)
+	}
+	if len(byID["go"].Checks) == 0 {
+		t.Error("Go should use the built-in checklist")
+	}
+}
+
+func TestGitattributesLanguagesManyPaths(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write(".gitattributes", "queries/** linguist-language=SQL\n")
+	repo.commit("initial")
+
+	// More path bytes than fit in the argument list of a single command
+	var paths []string
+	for i := 0; i < 40000; i++ {
+		paths = append(paths, fmt.Sprintf("queries/%s/report-%05d.txt", strings.Repeat("nested", 10), i))
+	}
+	overrides := gitattributesLanguages(repo.dir, paths)
+	if len(overrides) != len(paths) || overrides[paths[len(paths)-1]] != "SQL" {
+		t.Errorf("got %d overrides, want SQL for all %d paths", len(overrides), len(paths))
+	}
+}
+
+func TestFileHead(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("bin/run", "#!/bin/sh\n"+strings.Repeat("echo run\n", 200000))
+	sha := repo.commit("initial")
+
+	if head := fileHead(repo.dir, sha, "bin/run"); len(head) != fileHeadSize || !strings.HasPrefix(string(head), "#!/bin/sh\n") {
+		t.Errorf("fileHead() = %d bytes starting %q, want the first %d bytes", len(head), head[:min(len(head), 10)], fileHeadSize)
+	}
+	if head := fileHead(repo.dir, sha, "missing"); head != nil {
+		t.Errorf("fileHead() = %q, want nil for a missing file", head)
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"#!/usr/bin/env ruby\n", "ruby"},
+		{"#!/usr/local/bin/python3 -u\n", "python3"},
+		{"#!/usr/bin/env\n", ""},
+		{"echo hi\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := shebangInterpreter([]byte(tt.content)); got != tt.expected {
+			t.Errorf("shebangInterpreter(%q) = %q, want %q", tt.content, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultPromptLanguage is the language the prompt templates are written in
+const DefaultPromptLanguage = "en"
+
+// promptCatalog translates the instruction text of the prompt templates. Each
+// message maps a segment of template text, which may contain value actions such
+// as {{.CommentCount}}, to its translation; the translation must keep the same
+// actions. Text the catalog does not cover stays in English.
+type promptCatalog struct {
+	Name     string            `json:"name"`
+	Messages map[string]string `json:"messages"`
+}
+
+// PromptLanguages lists the languages the prompt can be rendered in
+func PromptLanguages() []string {
+	languages := []string{DefaultPromptLanguage}
+	files, _ := fs.Glob(templateFS, "templates/i18n/*.json")
+	for _, file := range files {
+		languages = append(languages, strings.TrimSuffix(path.Base(file), ".json"))
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// loadPromptCatalog reads the embedded message catalog of a language. English
+// needs no catalog and yields nil.
+func loadPromptCatalog(language string) (*promptCatalog, error) {
+	if language == "" || language == DefaultPromptLanguage {
+		return nil, nil
+	}
+	content, err := templateFS.ReadFile("templates/i18n/" + language + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown prompt language %q (known: %s)", language, strings.Join(PromptLanguages(), ", "))
+	}
+	var catalog promptCatalog
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse message catalog %s: %w", language, err)
+	}
+	return &catalog, nil
+}
+
+// translate replaces every catalog message in text with its translation.
+// Longer messages are matched first so a message never preempts one it is part of.
+func (c *promptCatalog) translate(text string) string {
+	messages := make([]string, 0, len(c.Messages))
+	for message := range c.Messages {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if len(messages[i]) != len(messages[j]) {
+			return len(messages[i]) > len(messages[j])
+		}
+		return messages[i] < messages[j]
+	})
+
+	pairs := make([]string, 0, 2*len(messages))
+	for _, message := range messages {
+		pairs = append(pairs, message, c.Messages[message])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// localizedPromptText returns the template text of a prompt version with its
+// instructions translated into language
+func localizedPromptText(name, language string) (string, error) {
+	text, err := PromptText(name)
+	if err != nil {
+		return "", err
+	}
+	catalog, err := loadPromptCatalog(language)
+	if err != nil || catalog == nil {
+		return text, err
+	}
+	return catalog.translate(text), nil
+}
+
+// ReviewLanguage returns the name of the language the model should write review
+// comments in, or an empty string for English
+func (s Settings) ReviewLanguage() string {
+	catalog, err := loadPromptCatalog(s.PromptLanguage)
+	if err != nil || catalog == nil {
+		return ""
+	}
+	return catalog.Name
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"io/fs"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPromptLanguages(t *testing.T) {
+	if got, want := PromptLanguages(), []string{"de", "en", "ja"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PromptLanguages() = %v, want %v", got, want)
+	}
+	if catalog, err := loadPromptCatalog("en"); catalog != nil || err != nil {
+		t.Errorf("loadPromptCatalog(en) = %v, %v, want no catalog", catalog, err)
+	}
+	if _, err := loadPromptCatalog("fr"); err == nil || !strings.Contains(err.Error(), "de, en, ja") {
+		t.Errorf("loadPromptCatalog(fr) error = %v, want the known languages listed", err)
+	}
+}
+
+// templateActions matches the actions of a template text
+var templateActions = regexp.MustCompile(`\{\{.*?\}\}`)
+
+func TestPromptCatalogs(t *testing.T) {
+	var sources strings.Builder
+	files, _ := fs.Glob(templateFS, "templates/*.tmpl")
+	for _, file := range files {
+		content, _ := templateFS.ReadFile(file)
+		sources.Write(content)
+	}
+
+	var keys []string
+	for _, language := range []string{"de", "ja"} {
+		catalog, err := loadPromptCatalog(language)
+		if err != nil {
+			t.Fatalf("loadPromptCatalog(%s) failed: %v", language, err)
+		}
+		if catalog.Name == "" {
+			t.Errorf("%s: catalog has no name", language)
+		}
+
+		var messages []string
+		for message, translation := range catalog.Messages {
+			messages = append(messages, message)
+			if !strings.Contains(sources.String(), message) {
+				t.Errorf("%s: message is not in any template: %q", language, message)
+			}
+			want := templateActions.FindAllString(message, -1)
+			got := templateActions.FindAllString(translation, -1)
+			sort.Strings(want)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: translation of %q has actions %v, want %v", language, message, got, want)
+			}
+		}
+		sort.Strings(messages)
+		if keys == nil {
+			keys = messages
+		} else if !reflect.DeepEqual(messages, keys) {
+			t.Errorf("%s: catalog translates different messages than de", language)
+		}
+	}
+}
+
+func TestRenderPromptLocalized(t *testing.T) {
+	tests := []struct {
+		template string
+		language string
+		expected []string
+		absent   []string
+	}{
+		{"v1", "de", []string{"Halte dich strikt an diese Richtlinien:", "Schreibe den Text jedes \"review\"-Felds auf Deutsch.", "Überblick über die Änderung: 1 Dateien geändert", `"line_number_start": 123,`}, []string{"Follow strictly these guidelines", "Change overview"}},
+		{"v2-strict", "ja", []string{"厳しくレビューしてください。", "本文は日本語で書いてください。", "| ファイル | 種類 |", `"type": "issue|performance|scalability|code_smell|new_category"`}, []string{"Review strictly", "Rules:"}},
+		{"v2-lenient", "en", []string{"Review leniently.", "Change overview"}, []string{"\"review\" field in"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template+"-"+tt.language, func(t *testing.T) {
+			settings := Settings{
+				RepoName:         "test-repo",
+				MergeBaseSha:     "abc",
+				SourceSha:        "def",
+				EnableBugs:       true,
+				CommentCount:     10,
+				ReviewOutputFile: "review.json",
+				PromptTemplate:   tt.template,
+				PromptLanguage:   tt.language,
+				Context: &ReviewContext{
+					Overview: &DiffOverview{Stats: DiffStats{Files: 1}, Files: []FileRisk{{Path: "a.go"}}},
+				},
+			}
+			prompt, err := RenderPrompt(settings)
+			if err != nil {
+				t.Fatalf("RenderPrompt() failed: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(string(prompt), expected) {
+					t.Errorf("Prompt should contain: %s", expected)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(string(prompt), absent) {
+					t.Errorf("Prompt should not contain: %s", absent)
+				}
+			}
+		})
+	}
+
+	english, englishHash := selectedPromptTemplate(Settings{})
+	name, germanHash := selectedPromptTemplate(Settings{PromptLanguage: "de"})
+	if name != english || germanHash == englishHash || germanHash == "" {
+		t.Errorf("selectedPromptTemplate(de) = %s, %s, want the v1 name with a different hash", name, germanHash)
+	}
+}
@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// redactedValue replaces the value of settings marked as secret
+const redactedValue = "[REDACTED]"
+
+// Manifest describes everything generated by a plugin run so downstream steps
+// can discover it without re-parsing environment variables
+type Manifest struct {
+	PluginVersion    string             `json:"plugin_version"`
+	TemplateName     string             `json:"template_name"`
+	TemplateHash     string             `json:"template_hash"`
+	PromptFile       string             `json:"prompt_file"`
+	ReviewOutputFile string             `json:"review_output_file"`
+	Settings         map[string]any     `json:"settings"`
+	Files            []ChangedFile      `json:"files"`
+	DiffStats        DiffStats          `json:"diff_stats"`
+	Overview         *DiffOverview      `json:"overview,omitempty"`
+	MissingTests     []MissingTest      `json:"missing_tests,omitempty"`
+	Coverage         *CoverageReport    `json:"coverage,omitempty"`
+	ToolFindings     *ToolFindings      `json:"tool_findings,omitempty"`
+	Dependencies     *DependencyReview  `json:"dependencies,omitempty"`
+	Migrations       *MigrationReview   `json:"migrations,omitempty"`
+	APIChanges       *APIReview         `json:"api_changes,omitempty"`
+	Functions        []FunctionContext  `json:"functions,omitempty"`
+	Callers          *CallerReview      `json:"callers,omitempty"`
+	Tokens           *TokenReport       `json:"tokens,omitempty"`
+	Languages        []LanguageGuidance `json:"languages,omitempty"`
+	PullRequest      *PullRequestInfo   `json:"pull_request,omitempty"`
+}
+
+// TemplateHash returns the content hash of a prompt template
+func TemplateHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewManifest builds the manifest for a run from the resolved settings and gathered context
+func NewManifest(settings Settings) Manifest {
+	files := []ChangedFile{}
+	ctx := settings.Context
+	if ctx == nil {
+		ctx = &ReviewContext{}
+	}
+	templateName, templateHash := selectedPromptTemplate(settings)
+	if ctx.Files != nil {
+		files = ctx.Files
+	}
+	return Manifest{
+		PluginVersion:    Version,
+		TemplateName:     templateName,
+		TemplateHash:     templateHash,
+		PromptFile:       settings.OutputFile,
+		ReviewOutputFile: settings.ReviewOutputFile,
+		Settings:         redactSettings(settings),
+		Files:            files,
+		DiffStats:        SummarizeDiff(files),
+		Overview:         ctx.Overview,
+		MissingTests:     ctx.MissingTests,
+		Coverage:         ctx.Coverage,
+		ToolFindings:     ctx.ToolFindings,
+		Dependencies:     ctx.Dependencies,
+		Migrations:       ctx.Migrations,
+		APIChanges:       ctx.APIChanges,
+		Functions:        ctx.Functions,
+		Callers:          ctx.Callers,
+		Tokens:           ctx.Tokens,
+		Languages:        ctx.Languages,
+		PullRequest:      ctx.PullRequest,
+	}
+}
+
+// WriteManifest writes the manifest as indented JSON to the manifest path of settings
+func WriteManifest(settings Settings, manifest Manifest) error {
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFileAtomic(settings.ManifestPath(), append(data, '\n'), mode, !settings.NoOverwrite); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// redactSettings converts a settings struct into a map keyed by its JSON names,
+// replacing any non-empty field tagged secret:"true"
+func redactSettings(v any) map[string]any {
+	result := make(map[string]any)
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		value := rv.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" && !rv.Field(i).IsZero() {
+			value = redactedValue
+		}
+		result[name] = value
+	}
+	return result
+}
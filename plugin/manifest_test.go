@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewManifest(t *testing.T) {
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "abc123",
+		SourceSha:        "def456",
+		CommentCount:     10,
+		OutputFile:       "../output/task.txt",
+		ReviewOutputFile: "../output/review.json",
+	}
+	files := []ChangedFile{
+		{Path: "a.go", Status: "M", Additions: 3, Deletions: 1},
+		{Path: "b.go", Status: "A", Additions: 7},
+	}
+
+	settings.Context = &ReviewContext{Files: files, Overview: AnalyzeRisk(files, nil)}
+	manifest := NewManifest(settings)
+
+	if manifest.PluginVersion != Version {
+		t.Errorf("PluginVersion = %v, want %v", manifest.PluginVersion, Version)
+	}
+	if !strings.HasPrefix(manifest.TemplateHash, "sha256:") || manifest.TemplateHash != TemplateHash(PromptTemplate) {
+		t.Errorf("TemplateHash = %v, want hash of PromptTemplate", manifest.TemplateHash)
+	}
+	if manifest.TemplateName != "v1" {
+		t.Errorf("TemplateName = %v, want v1", manifest.TemplateName)
+	}
+	if manifest.ReviewOutputFile != "../output/review.json" {
+		t.Errorf("ReviewOutputFile = %v, want ../output/review.json", manifest.ReviewOutputFile)
+	}
+	if manifest.Settings["repo_name"] != "test-repo" {
+		t.Errorf("Settings[repo_name] = %v, want test-repo", manifest.Settings["repo_name"])
+	}
+	if manifest.DiffStats != (DiffStats{Files: 2, Additions: 10, Deletions: 1}) {
+		t.Errorf("DiffStats = %+v, want 2 files +10 -1", manifest.DiffStats)
+	}
+	if manifest.Overview == nil || len(manifest.Overview.Files) != 2 || manifest.Overview.Files[0].Path != "b.go" {
+		t.Errorf("Overview = %+v, want both files with b.go first", manifest.Overview)
+	}
+}
+
+func TestNewManifestWithoutFiles(t *testing.T) {
+	manifest := NewManifest(Settings{})
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Failed to encode manifest: %v", err)
+	}
+	if !strings.Contains(string(data), `"files":[]`) {
+		t.Errorf("Manifest should encode an empty file list, got %s", data)
+	}
+}
+
+func TestRedactSettings(t *testing.T) {
+	type secretSettings struct {
+		Name     string `json:"name"`
+		Token    string `json:"token" secret:"true"`
+		Empty    string `json:"empty" secret:"true"`
+		Internal string `json:"-"`
+	}
+
+	result := redactSettings(secretSettings{Name: "repo", Token: "s3cr3t", Internal: "hidden"})
+
+	if result["name"] != "repo" {
+		t.Errorf("name = %v, want repo", result["name"])
+	}
+	if result["token"] != redactedValue {
+		t.Errorf("token = %v, want %v", result["token"], redactedValue)
+	}
+	if result["empty"] != "" {
+		t.Errorf("empty = %v, want empty string", result["empty"])
+	}
+	if _, ok := result["-"]; ok {
+		t.Error("Fields tagged json:\"-\" should be omitted")
+	}
+
+	// Free-form pull request text and runner paths never reach the manifest
+	settings := redactSettings(Settings{RepoName: "repo", PRDescription: "Fixes the login", Ticket: "PROJ-1", PREventFile: "/runner/event.json", OutputVarsFile: "/runner/output"})
+	for _, name := range []string{"pr_description", "ticket", "pr_event_file", "output_vars_file"} {
+		if settings[name] != redactedValue {
+			t.Errorf("%s = %v, want %v", name, settings[name], redactedValue)
+		}
+	}
+	if settings["repo_name"] != "repo" {
+		t.Errorf("repo_name = %v, want repo", settings["repo_name"])
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "manifest.json")
+	manifest := NewManifest(Settings{
+		RepoName: "test-repo",
+		Context:  &ReviewContext{Files: []ChangedFile{{Path: "a.go", Status: "A", Additions: 1}}},
+	})
+
+	if err := WriteManifest(Settings{ManifestFile: path}, manifest); err != nil {
+		t.Fatalf("WriteManifest() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var decoded Manifest
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("Manifest is not valid JSON: %v", err)
+	}
+	if len(decoded.Files) != 1 || decoded.Files[0].Path != "a.go" {
+		t.Errorf("Files = %+v, want a.go", decoded.Files)
+	}
+}
@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// migrationGlob recognizes the migration files of a framework
+type migrationGlob struct {
+	framework string
+	pattern   string
+}
+
+// defaultMigrationGlobs cover the layouts of common migration tools
+var defaultMigrationGlobs = []migrationGlob{
+	{"golang-migrate", "**/migrations/*.up.sql"},
+	{"golang-migrate", "**/migrations/*.down.sql"},
+	{"Flyway", "**/db/migration/V*__*.sql"},
+	{"Flyway", "**/db/migration/R__*.sql"},
+	{"Alembic", "**/alembic/versions/*.py"},
+	{"Alembic", "**/migrations/versions/*.py"},
+	{"Rails", "**/db/migrate/*.rb"},
+	{"Rails", "**/db/schema.rb"},
+	{"Rails", "**/db/structure.sql"},
+	{"Django", "**/migrations/[0-9]*.py"},
+	{"Prisma", "**/prisma/migrations/**/migration.sql"},
+	{"Liquibase", "**/db/changelog/**"},
+	{"Knex/Sequelize", "**/migrations/*.js"},
+	{"Knex/Sequelize", "**/migrations/*.ts"},
+	{"SQL", "**/migrations/**/*.sql"},
+	{"SQL", "**/migrate/**/*.sql"},
+}
+
+// migrationChecks is the checklist injected when migrations are part of the change
+var migrationChecks = []string{
+	"Locking: ALTER TABLE, index builds and constraint validation that lock large or hot tables; prefer CREATE INDEX CONCURRENTLY and NOT VALID constraints validated separately",
+	"Backfills: data updates run inside the schema migration or in one unbatched statement over a large table",
+	"Reversibility: a missing or incorrect down migration, or irreversible data loss in the rollback",
+	"NOT NULL columns added without a default to tables that already have rows",
+	"Compatibility with the running application version: dropped or renamed columns and tables still read by code deployed before the migration",
+	"Column type changes that rewrite the table or truncate existing data",
+}
+
+// MigrationFile is a changed migration with the risky statements found in its added lines
+type MigrationFile struct {
+	Path      string   `json:"path"`
+	Framework string   `json:"framework"`
+	Hints     []string `json:"hints,omitempty"`
+}
+
+// MigrationReview is the migration section of the prompt
+type MigrationReview struct {
+	Files  []MigrationFile `json:"files"`
+	Checks []string        `json:"checks"`
+}
+
+// migrationHint flags a risky statement in the added lines of a migration
+type migrationHint struct {
+	pattern *regexp.Regexp
+	exclude *regexp.Regexp
+	message string
+}
+
+// migrationHints are the statement patterns worth pointing out to the model
+var migrationHints = []migrationHint{
+	{
+		pattern: regexp.MustCompile(`(?i)\badd\s+(column\s+)?\S+\s+[^,;]*\bnot\s+null\b`),
+		exclude: regexp.MustCompile(`(?i)\bdefault\b`),
+		message: "adds a NOT NULL column without a default",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\bcreate\s+(unique\s+)?index\b`),
+		exclude: regexp.MustCompile(`(?i)\bconcurrently\b`),
+		message: "creates an index without CONCURRENTLY",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\bdrop\s+(table|column)\b|\bremove_column\b|\bdrop_table\b|op\.drop_(table|column)\b`),
+		message: "drops a table or column",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\brename\s+(column|to)\b|\brename_column\b|\brename_table\b`),
+		message: "renames a table or column",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\balter\s+column\s+\S+\s+(set\s+data\s+)?type\b|\bchange_column\b`),
+		message: "changes a column type",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)^\s*(update|delete\s+from)\b`),
+		message: "modifies data in the migration",
+	},
+}
+
+// ParseMigrationGlobs parses comma-separated extra globs and appends them to the built-in ones
+func ParseMigrationGlobs(spec string) ([]migrationGlob, error) {
+	globs := append([]migrationGlob(nil), defaultMigrationGlobs...)
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid migration glob %q: %w", pattern, err)
+		}
+		globs = append(globs, migrationGlob{framework: "custom", pattern: pattern})
+	}
+	return globs, nil
+}
+
+// FindMigrations returns the added or modified migration files and the risky
+// statements in their added lines, or nil when the change has no migrations
+func FindMigrations(files []ChangedFile, diffs []FileDiff, globs []migrationGlob) *MigrationReview {
+	added := make(map[string][]string)
+	for _, d := range diffs {
+		for _, h := range d.Hunks {
+			for _, l := range h.Lines {
+				if l.Kind == '+' {
+					added[d.Path] = append(added[d.Path], l.Text)
+				}
+			}
+		}
+	}
+	changed := make(map[string]bool)
+	for _, f := range files {
+		changed[f.Path] = true
+	}
+
+	review := &MigrationReview{Checks: migrationChecks}
+	for _, f := range files {
+		if f.Status == "D" {
+			continue
+		}
+		framework := migrationFramework(f.Path, globs)
+		if framework == "" {
+			continue
+		}
+
+		m := MigrationFile{Path: f.Path, Framework: framework}
+		for _, hint := range migrationHints {
+			for _, line := range added[f.Path] {
+				if hint.pattern.MatchString(line) && (hint.exclude == nil || !hint.exclude.MatchString(line)) {
+					m.Hints = append(m.Hints, hint.message)
+					break
+				}
+			}
+		}
+		if up, ok := strings.CutSuffix(f.Path, ".up.sql"); ok && f.Status == "A" && !changed[up+".down.sql"] {
+			m.Hints = append(m.Hints, "has no matching .down.sql migration in this change")
+		}
+		review.Files = append(review.Files, m)
+	}
+	if len(review.Files) == 0 {
+		return nil
+	}
+	return review
+}
+
+// migrationFramework returns the framework of the first glob matching p, or ""
+func migrationFramework(p string, globs []migrationGlob) string {
+	for _, g := range globs {
+		if matchGlob(g.pattern, p) {
+			return g.framework
+		}
+	}
+	return ""
+}
+
+// HintList joins the hints of a migration for the prompt
+func (m MigrationFile) HintList() string {
+	return strings.Join(m.Hints, "; ")
+}
+
+// matchGlob matches a slash-separated path against a pattern in which ** matches
+// any number of directories and other segments follow path.Match
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"**/migrations/*.up.sql", "migrations/0001_init.up.sql", true},
+		{"**/migrations/*.up.sql", "svc/db/migrations/0001_init.up.sql", true},
+		{"**/migrations/*.up.sql", "svc/migrations/nested/0001_init.up.sql", false},
+		{"**/db/changelog/**", "src/main/resources/db/changelog/2024/01.yaml", true},
+		{"db/migrate/*.rb", "db/migrate/20240101_add_users.rb", true},
+		{"db/migrate/*.rb", "app/db/migrate/20240101_add_users.rb", false},
+		{"**/migrations/[0-9]*.py", "app/migrations/0002_auto.py", true},
+		{"**/migrations/[0-9]*.py", "app/migrations/__init__.py", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindMigrations(t *testing.T) {
+	files := []ChangedFile{
+		{Path: "db/migrations/0003_users.up.sql", Status: "A"},
+		{Path: "db/migrations/0004_orders.up.sql", Status: "A"},
+		{Path: "db/migrations/0004_orders.down.sql", Status: "A"},
+		{Path: "src/main/resources/db/migration/V5__add_index.sql", Status: "A"},
+		{Path: "db/migrate/20240101_old.rb", Status: "D"},
+		{Path: "app/models/user.go", Status: "M"},
+	}
+	added := func(path string, lines ...string) FileDiff {
+		var hunk Hunk
+		for i, l := range lines {
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: '+', NewLine: i + 1, Text: l})
+		}
+		return FileDiff{Path: path, Hunks: []Hunk{hunk}}
+	}
+	diffs := []FileDiff{
+		added("db/migrations/0003_users.up.sql",
+			"ALTER TABLE users ADD COLUMN tenant_id bigint NOT NULL;",
+			"ALTER TABLE users ADD COLUMN active boolean NOT NULL DEFAULT true;",
+			"UPDATE users SET active = true;"),
+		added("db/migrations/0004_orders.up.sql", "ALTER TABLE orders DROP COLUMN legacy;"),
+		added("src/main/resources/db/migration/V5__add_index.sql", "CREATE INDEX CONCURRENTLY idx_a ON a (b);", "CREATE INDEX idx_c ON c (d);"),
+	}
+
+	review := FindMigrations(files, diffs, defaultMigrationGlobs)
+	if review == nil {
+		t.Fatal("FindMigrations() = nil, want migrations")
+	}
+	want := []MigrationFile{
+		{Path: "db/migrations/0003_users.up.sql", Framework: "golang-migrate", Hints: []string{
+			"adds a NOT NULL column without a default",
+			"modifies data in the migration",
+			"has no matching .down.sql migration in this change",
+		}},
+		{Path: "db/migrations/0004_orders.up.sql", Framework: "golang-migrate", Hints: []string{"drops a table or column"}},
+		{Path: "db/migrations/0004_orders.down.sql", Framework: "golang-migrate"},
+		{Path: "src/main/resources/db/migration/V5__add_index.sql", Framework: "Flyway", Hints: []string{"creates an index without CONCURRENTLY"}},
+	}
+	if !reflect.DeepEqual(review.Files, want) {
+		t.Errorf("Files =\n%+v\nwant\n%+v", review.Files, want)
+	}
+
+	if review := FindMigrations(files[5:], nil, defaultMigrationGlobs); review != nil {
+		t.Errorf("FindMigrations() = %+v, want nil without migrations", review)
+	}
+}
+
+func TestParseMigrationGlobs(t *testing.T) {
+	globs, err := ParseMigrationGlobs("schema/**/*.cql, ")
+	if err != nil {
+		t.Fatalf("ParseMigrationGlobs() failed: %v", err)
+	}
+	if got := migrationFramework("schema/v2/001.cql", globs); got != "custom" {
+		t.Errorf("migrationFramework() = %q, want custom", got)
+	}
+	if _, err := ParseMigrationGlobs("db/[migrations"); err == nil {
+		t.Error("ParseMigrationGlobs() should reject a malformed glob")
+	}
+}
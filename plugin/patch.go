@@ -0,0 +1,298 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// patchContext is the number of unchanged lines around each change in a patch
+const patchContext = 3
+
+// Suggestion is a suggestion block mapped onto the lines it replaces
+type Suggestion struct {
+	Review   int
+	FilePath string
+	Start    int
+	End      int
+	Lines    []string
+}
+
+// String describes the review comment and lines of a suggestion
+func (s Suggestion) String() string {
+	return fmt.Sprintf("review %d (%s:%d-%d)", s.Review, s.FilePath, s.Start, s.End)
+}
+
+// RejectedSuggestion is a suggestion left out of the patch, with the reason
+type RejectedSuggestion struct {
+	Suggestion
+	Reason string
+}
+
+// PatchedFile is a file with the accepted suggestions applied, in line order
+type PatchedFile struct {
+	Path        string
+	Before      []string
+	After       []string
+	Suggestions []Suggestion
+	// NoEOL is set when the file does not end with a newline
+	NoEOL bool
+}
+
+// SuggestionPatch holds the files changed by the accepted suggestions of a review
+type SuggestionPatch struct {
+	Files    []PatchedFile
+	Applied  []Suggestion
+	Rejected []RejectedSuggestion
+}
+
+// PlanSuggestions maps the suggestion blocks of output onto the files at sha.
+// A suggestion is rejected when its file is not in diffs, when its range lies
+// outside the file or does not touch an added line, or when it overlaps a
+// suggestion accepted from an earlier comment.
+func PlanSuggestions(dir, sha string, output ReviewOutput, diffs []FileDiff) *SuggestionPatch {
+	added := make(map[string]map[int]bool)
+	for _, d := range diffs {
+		lines := make(map[int]bool)
+		for _, l := range d.AddedLines() {
+			lines[l] = true
+		}
+		added[d.Path] = lines
+	}
+
+	patch := &SuggestionPatch{}
+	files := make(map[string]*PatchedFile)
+	var paths []string
+	for i, r := range output.Reviews {
+		_, blocks := ExtractSuggestions(r.Review)
+		for _, block := range blocks {
+			s := Suggestion{Review: i, FilePath: r.FilePath, Start: r.LineNumberStart, End: r.LineNumberEnd, Lines: block}
+			file := files[s.FilePath]
+			if file == nil && added[s.FilePath] != nil {
+				file = readPatchedFile(dir, sha, s.FilePath)
+				files[s.FilePath] = file
+				paths = append(paths, s.FilePath)
+			}
+
+			reason := ""
+			switch {
+			case file == nil:
+				reason = "file is not changed by the pull request"
+			case s.Start < 1 || s.End > len(file.Before):
+				reason = fmt.Sprintf("range is outside the file, which has %d lines at %s", len(file.Before), sha)
+			case !touchesLines(added[s.FilePath], s.Start, s.End):
+				reason = "range does not touch an added line"
+			}
+			for _, other := range patch.Applied {
+				if reason == "" && other.FilePath == s.FilePath && s.Start <= other.End && other.Start <= s.End {
+					reason = "range overlaps the suggestion of " + other.String()
+				}
+			}
+			if reason != "" {
+				patch.Rejected = append(patch.Rejected, RejectedSuggestion{s, reason})
+				continue
+			}
+			patch.Applied = append(patch.Applied, s)
+			file.Suggestions = append(file.Suggestions, s)
+		}
+	}
+
+	for _, path := range paths {
+		file := files[path]
+		if len(file.Suggestions) == 0 {
+			continue
+		}
+		sort.Slice(file.Suggestions, func(i, j int) bool { return file.Suggestions[i].Start < file.Suggestions[j].Start })
+		next := 1
+		for _, s := range file.Suggestions {
+			file.After = append(file.After, file.Before[next-1:s.Start-1]...)
+			file.After = append(file.After, s.Lines...)
+			next = s.End + 1
+		}
+		file.After = append(file.After, file.Before[next-1:]...)
+		patch.Files = append(patch.Files, *file)
+	}
+	return patch
+}
+
+// readPatchedFile reads a file at sha as the starting point of a patch
+func readPatchedFile(dir, sha, path string) *PatchedFile {
+	content := fileAt(dir, sha, path)
+	file := &PatchedFile{Path: path, NoEOL: content != "" && !strings.HasSuffix(content, "\n")}
+	if content != "" {
+		file.Before = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	return file
+}
+
+// touchesLines reports whether any line from start to end is in lines
+func touchesLines(lines map[int]bool, start, end int) bool {
+	for l := start; l <= end; l++ {
+		if lines[l] {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified renders the patch as a unified diff that git apply accepts
+func (p *SuggestionPatch) Unified() string {
+	var b strings.Builder
+	for _, file := range p.Files {
+		ops := file.ops()
+		hunks := patchHunks(ops)
+		if len(hunks) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", file.Path, file.Path, file.Path, file.Path)
+		for _, h := range hunks {
+			b.WriteString(file.hunk(ops[h[0]:h[1]], ops[:h[0]]))
+		}
+	}
+	return b.String()
+}
+
+// ops returns the changes that turn the file into its patched version, one per
+// line of either side
+func (f PatchedFile) ops() []DiffLine {
+	var ops []DiffLine
+	oldLine, newLine := 1, 1
+	keep := func(end int) {
+		for ; oldLine < end; oldLine, newLine = oldLine+1, newLine+1 {
+			ops = append(ops, DiffLine{Kind: ' ', OldLine: oldLine, NewLine: newLine, Text: f.Before[oldLine-1]})
+		}
+	}
+	for _, s := range f.Suggestions {
+		keep(s.Start)
+		for _, l := range lineDiff(f.Before[s.Start-1:s.End], s.Lines, 0) {
+			l.OldLine, l.NewLine = 0, 0
+			if l.Kind != '+' {
+				l.OldLine = oldLine
+				oldLine++
+			}
+			if l.Kind != '-' {
+				l.NewLine = newLine
+				newLine++
+			}
+			ops = append(ops, l)
+		}
+	}
+	keep(len(f.Before) + 1)
+
+	// Without a final newline, the last line of each side carries a marker; a
+	// shared line that is last on only one side has to change
+	if f.NoEOL {
+		for i, l := range ops {
+			if l.Kind == ' ' && (l.OldLine == len(f.Before)) != (l.NewLine == len(f.After)) {
+				split := []DiffLine{{Kind: '-', OldLine: l.OldLine, Text: l.Text}, {Kind: '+', NewLine: l.NewLine, Text: l.Text}}
+				ops = append(ops[:i], append(split, ops[i+1:]...)...)
+				break
+			}
+		}
+	}
+	return ops
+}
+
+// patchHunks groups the changes in ops into hunks with up to patchContext
+// unchanged lines around them and returns the start and end index of each
+func patchHunks(ops []DiffLine) [][2]int {
+	var hunks [][2]int
+	for i, l := range ops {
+		if l.Kind == ' ' {
+			continue
+		}
+		start := max(i-patchContext, 0)
+		end := min(i+patchContext+1, len(ops))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+			continue
+		}
+		hunks = append(hunks, [2]int{start, end})
+	}
+	return hunks
+}
+
+// hunk renders one hunk of the file's patch; preceding are the changes before it
+func (f PatchedFile) hunk(ops, preceding []DiffLine) string {
+	oldBefore, newBefore := 0, 0
+	for _, l := range preceding {
+		if l.Kind != '+' {
+			oldBefore++
+		}
+		if l.Kind != '-' {
+			newBefore++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, l := range ops {
+		if l.Kind != '+' {
+			oldCount++
+		}
+		if l.Kind != '-' {
+			newCount++
+		}
+	}
+	// An empty side is numbered after the line before the hunk
+	oldStart, newStart := oldBefore+1, newBefore+1
+	if oldCount == 0 {
+		oldStart = oldBefore
+	}
+	if newCount == 0 {
+		newStart = newBefore
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, l := range ops {
+		b.WriteString(string(l.Kind) + l.Text + "\n")
+		lastOld := l.Kind != '+' && l.OldLine == len(f.Before)
+		lastNew := l.Kind != '-' && l.NewLine == len(f.After)
+		if f.NoEOL && (lastOld || lastNew) {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	return b.String()
+}
+
+// Apply writes the patched files into the working tree at dir. Every file must
+// still match the version the patch was made from; nothing is written unless
+// they all do.
+func (p *SuggestionPatch) Apply(dir string) error {
+	modes := make([]os.FileMode, len(p.Files))
+	for i, file := range p.Files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to apply suggestions: %w", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to apply suggestions: %w", err)
+		}
+		if string(content) != file.content(file.Before) {
+			return fmt.Errorf("failed to apply suggestions: %s differs from the source commit", file.Path)
+		}
+		modes[i] = info.Mode().Perm()
+	}
+	for i, file := range p.Files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := writeFileAtomic(path, []byte(file.content(file.After)), modes[i], true); err != nil {
+			return fmt.Errorf("failed to apply suggestions to %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// content joins lines into the text of the file
+func (f PatchedFile) content(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, "\n")
+	if !f.NoEOL {
+		text += "\n"
+	}
+	return text
+}
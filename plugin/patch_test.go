@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// suggestionRepo commits a base and a change to main.go and returns the
+// repository with its diff
+func suggestionRepo(t *testing.T) (*testRepo, string, []FileDiff) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n\nfunc main() {\n}\n")
+	repo.write("util.go", "package main\n")
+	base := repo.commit("initial")
+	repo.write("main.go", "package main\n\nimport \"os\"\n\nfunc main() {\n\tos.Open(\"a\")\n\tos.Open(\"b\")\n}\n\nfunc helper() {}\n")
+	head := repo.commit("change")
+
+	diffs, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+	return repo, head, diffs
+}
+
+// suggest returns a review comment with a suggestion block
+func suggest(path string, start, end int, lines ...string) ReviewComment {
+	review := "Fix this.\n```suggestion\n" + strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		review += "\n"
+	}
+	return ReviewComment{FilePath: path, LineNumberStart: start, LineNumberEnd: end, Type: "bug", Review: review + "```"}
+}
+
+func TestPlanSuggestions(t *testing.T) {
+	repo, head, diffs := suggestionRepo(t)
+	output := ReviewOutput{Reviews: []ReviewComment{
+		suggest("main.go", 6, 7, "\tif _, err := os.Open(\"a\"); err != nil {", "\t\tpanic(err)", "\t}"),
+		suggest("main.go", 7, 7, "\tos.Open(\"c\")"),
+		suggest("main.go", 1, 1, "package app"),
+		suggest("util.go", 1, 1, "package util"),
+		suggest("main.go", 10, 12, "func helper() {", "}"),
+		{FilePath: "main.go", LineNumberStart: 3, LineNumberEnd: 3, Review: "No suggestion here."},
+		suggest("main.go", 10, 10),
+	}}
+
+	patch := PlanSuggestions(repo.dir, head, output, diffs)
+	var rejected []string
+	for _, r := range patch.Rejected {
+		rejected = append(rejected, r.Suggestion.String()+": "+r.Reason)
+	}
+	want := []string{
+		"review 1 (main.go:7-7): range overlaps the suggestion of review 0 (main.go:6-7)",
+		"review 2 (main.go:1-1): range does not touch an added line",
+		"review 3 (util.go:1-1): file is not changed by the pull request",
+		"review 4 (main.go:10-12): range is outside the file, which has 10 lines at " + head,
+	}
+	if strings.Join(rejected, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Rejected =\n%s\nwant\n%s", strings.Join(rejected, "\n"), strings.Join(want, "\n"))
+	}
+	if len(patch.Applied) != 2 || patch.Applied[0].Review != 0 || patch.Applied[1].Review != 6 {
+		t.Errorf("Applied = %v, want reviews 0 and 6", patch.Applied)
+	}
+
+	wantPatch := "diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -3,8 +3,8 @@\n" +
+		" import \"os\"\n" +
+		" \n" +
+		" func main() {\n" +
+		"-\tos.Open(\"a\")\n" +
+		"-\tos.Open(\"b\")\n" +
+		"+\tif _, err := os.Open(\"a\"); err != nil {\n" +
+		"+\t\tpanic(err)\n" +
+		"+\t}\n" +
+		" }\n" +
+		" \n" +
+		"-func helper() {}\n"
+	if got := patch.Unified(); got != wantPatch {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, wantPatch)
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "suggestions.patch")
+	if err := os.WriteFile(patchFile, []byte(patch.Unified()), 0644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+	repo.git("apply", "--check", patchFile)
+}
+
+func TestSuggestionPatchNoEOL(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("notes.txt", "one")
+	base := repo.commit("initial")
+	repo.write("notes.txt", "one\ntwo")
+	head := repo.commit("change")
+	diffs, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+
+	output := ReviewOutput{Reviews: []ReviewComment{suggest("notes.txt", 2, 2, "two", "three")}}
+	patch := PlanSuggestions(repo.dir, head, output, diffs)
+	want := "diff --git a/notes.txt b/notes.txt\n--- a/notes.txt\n+++ b/notes.txt\n" +
+		"@@ -1,2 +1,3 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"\\ No newline at end of file\n" +
+		"+two\n" +
+		"+three\n" +
+		"\\ No newline at end of file\n"
+	if got := patch.Unified(); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "suggestions.patch")
+	if err := os.WriteFile(patchFile, []byte(want), 0644); err != nil {
+		t.Fatalf("Failed to write patch: %v", err)
+	}
+	repo.git("apply", patchFile)
+	if content, _ := os.ReadFile(filepath.Join(repo.dir, "notes.txt")); string(content) != "one\ntwo\nthree" {
+		t.Errorf("git apply produced %q", content)
+	}
+}
+
+func TestSuggestionPatchApply(t *testing.T) {
+	repo, head, diffs := suggestionRepo(t)
+	output := ReviewOutput{Reviews: []ReviewComment{suggest("main.go", 10, 10, "func helper() { os.Exit(1) }")}}
+	patch := PlanSuggestions(repo.dir, head, output, diffs)
+
+	repo.write("main.go", "package main\n")
+	if err := patch.Apply(repo.dir); err == nil || !strings.Contains(err.Error(), "main.go differs from the source commit") {
+		t.Errorf("Apply() error = %v, want the modified working tree reported", err)
+	}
+
+	repo.git("checkout", "--", "main.go")
+	if err := patch.Apply(repo.dir); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(repo.dir, "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
+	if !strings.HasSuffix(string(content), "\tos.Open(\"b\")\n}\n\nfunc helper() { os.Exit(1) }\n") {
+		t.Errorf("main.go after Apply() =\n%s", content)
+	}
+}
+
+func TestSuggestionPatchApplyChecksEveryFile(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("a.txt", "one\n")
+	repo.write("b.txt", "one\n")
+	base := repo.commit("initial")
+	repo.write("a.txt", "one\ntwo\n")
+	repo.write("b.txt", "one\ntwo\n")
+	head := repo.commit("change")
+	diffs, err := LoadDiff(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("LoadDiff() failed: %v", err)
+	}
+	output := ReviewOutput{Reviews: []ReviewComment{suggest("a.txt", 2, 2, "TWO"), suggest("b.txt", 2, 2, "TWO")}}
+	patch := PlanSuggestions(repo.dir, head, output, diffs)
+
+	repo.write("b.txt", "changed\n")
+	if err := patch.Apply(repo.dir); err == nil || !strings.Contains(err.Error(), "b.txt differs from the source commit") {
+		t.Errorf("Apply() error = %v, want the modified b.txt reported", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(repo.dir, "a.txt")); string(content) != "one\ntwo\n" {
+		t.Errorf("a.txt = %q, want it unchanged when another file does not match", content)
+	}
+}
+
+func TestRunPatchRequiresDiff(t *testing.T) {
+	os.Clearenv()
+	reviewFile := filepath.Join(t.TempDir(), "review.json")
+	if err := os.WriteFile(reviewFile, []byte(`{"reviews": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := Run([]string{"patch", "-review-output-file", reviewFile}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "merge base and source SHAs are required") {
+		t.Errorf("Run() = %d, want 1 with the missing SHAs reported, got:\n%s", code, stderr.String())
+	}
+}
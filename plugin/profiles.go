@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Strictness levels of a profile
+const (
+	strictnessStrict   = "strict"
+	strictnessBalanced = "balanced"
+	strictnessLenient  = "lenient"
+)
+
+// Profile bundles a reviewer persona with the review settings it implies
+type Profile struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Tone         string   `json:"tone,omitempty"`
+	Strictness   string   `json:"strictness,omitempty"`
+	Categories   []string `json:"categories,omitempty"`
+	CommentCount int      `json:"comment_count,omitempty"`
+	Rules        []string `json:"rules,omitempty"`
+}
+
+// reviewCategory ties a profile category to the setting that enables it
+type reviewCategory struct {
+	name    string
+	setting string
+	field   func(*Settings) *bool
+}
+
+// reviewCategories lists the categories a profile can select
+var reviewCategories = []reviewCategory{
+	{"bugs", "enable_bugs", func(s *Settings) *bool { return &s.EnableBugs }},
+	{"performance", "enable_performance", func(s *Settings) *bool { return &s.EnablePerformance }},
+	{"scalability", "enable_scalability", func(s *Settings) *bool { return &s.EnableScalability }},
+	{"code_smell", "enable_code_smell", func(s *Settings) *bool { return &s.EnableCodeSmell }},
+	{"test_coverage", "enable_test_coverage", func(s *Settings) *bool { return &s.EnableTestCoverage }},
+}
+
+// builtinProfiles are the profiles available without any repository configuration.
+// A <profiles_path>/<name>.json file with the same name replaces one of them.
+var builtinProfiles = []Profile{
+	{
+		Name:         "security-auditor",
+		Description:  "Strict security review for infrastructure and services",
+		Tone:         "Review as a security auditor: assume every input is hostile and every change may widen the attack surface. Be terse and precise, and state the impact of each finding.",
+		Strictness:   strictnessStrict,
+		Categories:   []string{"bugs", "scalability"},
+		CommentCount: 20,
+		Rules: []string{
+			"Check every changed input path for injection into SQL, shell commands, file paths, templates and deserializers.",
+			"Flag missing or weakened authentication and authorization checks and privilege escalation paths.",
+			"Flag secrets, tokens and credentials in code, configuration and log output.",
+			"Flag insecure defaults such as disabled TLS verification, permissive CORS, world-readable files and wildcard permissions.",
+			"Report security problems using the type \"security\".",
+		},
+	},
+	{
+		Name:         "mentor",
+		Description:  "Explanatory reviews for developers early in their career",
+		Tone:         "Review as a mentor for a developer early in their career: explain why each problem matters and how to fix it, and name the language feature or idiom involved. Be encouraging, but do not write comments that only praise.",
+		Strictness:   strictnessBalanced,
+		Categories:   []string{"bugs", "performance", "code_smell", "test_coverage"},
+		CommentCount: 15,
+		Rules: []string{
+			"Explain a recurring mistake once, on its first occurrence, instead of repeating the comment.",
+			"Include a suggestion block with the corrected code whenever the fix is local to the commented lines.",
+		},
+	},
+	{
+		Name:         "bugs-only",
+		Description:  "Only problems that should block the merge",
+		Tone:         "Review as a busy senior engineer: only raise problems that should block the merge.",
+		Strictness:   strictnessLenient,
+		Categories:   []string{"bugs"},
+		CommentCount: 5,
+	},
+}
+
+// ProfileNames lists the built-in profiles
+func ProfileNames() []string {
+	var names []string
+	for _, p := range builtinProfiles {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile returns the profile called name, preferring <dir>/<name>.json
+// over the built-in profile of the same name
+func LoadProfile(dir, name string) (*Profile, error) {
+	var profile *Profile
+	if dir != "" {
+		content, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		if err == nil {
+			profile = &Profile{}
+			decoder := json.NewDecoder(bytes.NewReader(content))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(profile); err != nil {
+				return nil, fmt.Errorf("failed to parse profile %s: %w", filepath.Join(dir, name+".json"), err)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read profile: %w", err)
+		}
+	}
+	if profile == nil {
+		for _, p := range builtinProfiles {
+			if p.Name == name {
+				profile = &p
+				break
+			}
+		}
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("unknown profile %q: not a built-in profile (%s) and no %s.json in %s", name, strings.Join(ProfileNames(), ", "), name, dir)
+	}
+
+	profile.Name = name
+	if profile.Strictness == "" {
+		profile.Strictness = strictnessBalanced
+	}
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", name, err)
+	}
+	return profile, nil
+}
+
+// validate reports values of a profile that cannot be applied
+func (p *Profile) validate() error {
+	var errs []error
+	switch p.Strictness {
+	case strictnessStrict, strictnessBalanced, strictnessLenient:
+	default:
+		errs = append(errs, fmt.Errorf("strictness must be strict, balanced or lenient, got %q", p.Strictness))
+	}
+	for _, name := range p.Categories {
+		if findReviewCategory(name) == nil {
+			errs = append(errs, fmt.Errorf("unknown category %q", name))
+		}
+	}
+	if p.CommentCount < 0 {
+		errs = append(errs, fmt.Errorf("comment_count must not be negative, got %d", p.CommentCount))
+	}
+	return errors.Join(errs...)
+}
+
+// findReviewCategory looks up a profile category by name
+func findReviewCategory(name string) *reviewCategory {
+	for i := range reviewCategories {
+		if reviewCategories[i].name == name {
+			return &reviewCategories[i]
+		}
+	}
+	return nil
+}
+
+// WithProfile applies the profile selected by settings: its comment count and
+// category set replace the corresponding settings unless they were set
+// explicitly, and its tone, strictness and rules are rendered into the prompt.
+// explicit holds the names of the settings set through flags or the environment.
+func (s Settings) WithProfile(explicit map[string]bool) (Settings, error) {
+	if s.Profile == "" {
+		return s, nil
+	}
+	profile, err := LoadProfile(s.ProfilesPath, s.Profile)
+	if err != nil {
+		return s, err
+	}
+
+	if profile.CommentCount > 0 && !explicit["comment_count"] {
+		s.CommentCount = profile.CommentCount
+	}
+	if len(profile.Categories) > 0 {
+		selected := make(map[string]bool)
+		for _, name := range profile.Categories {
+			selected[name] = true
+		}
+		for _, c := range reviewCategories {
+			if !explicit[c.setting] {
+				*c.field(&s) = selected[c.name]
+			}
+		}
+	}
+	s.ReviewProfile = profile
+	return s, nil
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "infra.json"), []byte(`{"tone": "Review as an SRE.", "categories": ["bugs", "scalability"], "comment_count": 8, "rules": ["Flag missing timeouts."]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "mentor.json"), []byte(`{"tone": "Be kind.", "strictness": "lenient"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "typo.json"), []byte(`{"comment_cout": 3}`), 0644)
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"strictness": "harsh", "categories": ["style"], "comment_count": -1}`), 0644)
+
+	profile, err := LoadProfile(dir, "infra")
+	if err != nil {
+		t.Fatalf("LoadProfile(infra) failed: %v", err)
+	}
+	want := &Profile{Name: "infra", Tone: "Review as an SRE.", Strictness: strictnessBalanced, Categories: []string{"bugs", "scalability"}, CommentCount: 8, Rules: []string{"Flag missing timeouts."}}
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("LoadProfile(infra) = %+v, want %+v", profile, want)
+	}
+
+	if profile, err := LoadProfile(dir, "mentor"); err != nil || profile.Tone != "Be kind." || profile.Rules != nil {
+		t.Errorf("LoadProfile(mentor) = %+v, %v, want the repository profile to replace the built-in one", profile, err)
+	}
+	if profile, err := LoadProfile(dir, "security-auditor"); err != nil || profile.Strictness != strictnessStrict || len(profile.Rules) == 0 {
+		t.Errorf("LoadProfile(security-auditor) = %+v, %v, want the built-in profile", profile, err)
+	}
+
+	for name, expected := range map[string][]string{
+		"typo":    {"comment_cout"},
+		"bad":     {"strictness must be strict, balanced or lenient", `unknown category "style"`, "comment_count must not be negative"},
+		"missing": {`unknown profile "missing"`, "bugs-only, mentor, security-auditor"},
+	} {
+		_, err := LoadProfile(dir, name)
+		for _, want := range expected {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("LoadProfile(%s) error = %v, want it to contain %q", name, err, want)
+			}
+		}
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	settings := Settings{
+		Profile:           "bugs-only",
+		CommentCount:      10,
+		EnableBugs:        false,
+		EnablePerformance: true,
+		EnableCodeSmell:   true,
+	}
+
+	resolved, err := settings.WithProfile(map[string]bool{"enable_code_smell": true})
+	if err != nil {
+		t.Fatalf("WithProfile() failed: %v", err)
+	}
+	if resolved.CommentCount != 5 || !resolved.EnableBugs || resolved.EnablePerformance || !resolved.EnableCodeSmell {
+		t.Errorf("WithProfile() = comment count %d, bugs %v, performance %v, code smell %v, want 5, true, false and the explicit true",
+			resolved.CommentCount, resolved.EnableBugs, resolved.EnablePerformance, resolved.EnableCodeSmell)
+	}
+	if resolved.ReviewProfile == nil || resolved.ReviewProfile.Name != "bugs-only" {
+		t.Errorf("ReviewProfile = %+v, want bugs-only", resolved.ReviewProfile)
+	}
+
+	if resolved, err := (Settings{CommentCount: 7}).WithProfile(nil); err != nil || resolved.ReviewProfile != nil || resolved.CommentCount != 7 {
+		t.Errorf("WithProfile() without a profile = %+v, %v, want the settings unchanged", resolved, err)
+	}
+}
+
+func TestPromptTemplateProfile(t *testing.T) {
+	for _, version := range PromptTemplateNames() {
+		t.Run(version, func(t *testing.T) {
+			settings, err := Settings{RepoName: "test-repo", CommentCount: 10, PromptTemplate: version, Profile: "security-auditor"}.WithProfile(nil)
+			if err != nil {
+				t.Fatalf("WithProfile() failed: %v", err)
+			}
+			prompt, err := RenderPrompt(settings)
+			if err != nil {
+				t.Fatalf("RenderPrompt() failed: %v", err)
+			}
+			for _, expected := range []string{
+				"\nReview as a security auditor: assume every input is hostile",
+				"\nBe strict: report every problem you are confident about",
+				"\n- Flag secrets, tokens and credentials in code, configuration and log output.\n",
+				"more than 20 comments",
+			} {
+				if !strings.Contains(string(prompt), expected) {
+					t.Errorf("Prompt should contain: %q", expected)
+				}
+			}
+		})
+	}
+}
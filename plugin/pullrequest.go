@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Limits that keep author-provided context from crowding out the diff
+const (
+	maxContextTextBytes = 8000
+	maxCommitLog        = 50
+)
+
+// Commit is a single commit between the merge base and the source SHA
+type Commit struct {
+	SHA     string `json:"sha"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+}
+
+// ShortSHA returns the abbreviated commit hash
+func (c Commit) ShortSHA() string {
+	if len(c.SHA) > 8 {
+		return c.SHA[:8]
+	}
+	return c.SHA
+}
+
+// IndentedBody returns the commit body indented for nesting under a list item
+func (c Commit) IndentedBody() string {
+	if c.Body == "" {
+		return ""
+	}
+	lines := strings.Split(c.Body, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PullRequestInfo is what the author says the change does
+type PullRequestInfo struct {
+	Title          string   `json:"title,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Ticket         string   `json:"ticket,omitempty"`
+	Commits        []Commit `json:"commits,omitempty"`
+	CommitsOmitted int      `json:"commits_omitted,omitempty"`
+}
+
+// pullRequestEvent is the subset of a GitHub/Gitea pull_request event payload the plugin reads
+type pullRequestEvent struct {
+	PullRequest struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	} `json:"pull_request"`
+}
+
+// ListCommits returns the commits reachable from head but not from base, oldest first
+func ListCommits(dir, base, head string) ([]Commit, error) {
+	out, err := runGit(dir, "log", "--reverse", "--format=%H%x1f%an%x1f%s%x1f%b%x1e", base+".."+head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("malformed commit record %q", record)
+		}
+		commits = append(commits, Commit{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Subject: fields[2],
+			Body:    strings.TrimSpace(fields[3]),
+		})
+	}
+	return commits, nil
+}
+
+// CollectPullRequestInfo resolves the title, description and ticket from settings,
+// files and the CI event payload, and lists the commits under review.
+// Problems reading optional sources are returned as warnings.
+func CollectPullRequestInfo(dir string, settings Settings) (*PullRequestInfo, []string) {
+	var warnings []string
+	info := &PullRequestInfo{Title: settings.PRTitle, Description: settings.PRDescription}
+
+	if info.Description == "" && settings.PRDescriptionFile != "" {
+		text, err := os.ReadFile(settings.PRDescriptionFile)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not read pr_description_file: %v", err))
+		}
+		info.Description = string(text)
+	}
+
+	if settings.PREventFile != "" && (info.Title == "" || info.Description == "") {
+		event, err := readPullRequestEvent(settings.PREventFile)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		if info.Title == "" {
+			info.Title = event.PullRequest.Title
+		}
+		if info.Description == "" {
+			info.Description = event.PullRequest.Body
+		}
+	}
+
+	info.Ticket = settings.Ticket
+	if info.Ticket == "" && settings.TicketFile != "" {
+		text, err := os.ReadFile(settings.TicketFile)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not read ticket_file: %v", err))
+		}
+		info.Ticket = string(text)
+	}
+
+	info.Title = strings.TrimSpace(info.Title)
+	info.Description = truncateText(strings.TrimSpace(info.Description), maxContextTextBytes)
+	info.Ticket = truncateText(strings.TrimSpace(info.Ticket), maxContextTextBytes)
+
+	if settings.IncludeCommitLog || settings.EnableCommitReview {
+		commits, err := ListCommits(dir, settings.MergeBaseSha, settings.SourceSha)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		if len(commits) > maxCommitLog {
+			info.CommitsOmitted = len(commits) - maxCommitLog
+			commits = commits[len(commits)-maxCommitLog:]
+		}
+		info.Commits = commits
+	}
+
+	if info.Title == "" && info.Description == "" && info.Ticket == "" && len(info.Commits) == 0 {
+		return nil, warnings
+	}
+	return info, warnings
+}
+
+// readPullRequestEvent decodes a pull_request event payload
+func readPullRequestEvent(path string) (pullRequestEvent, error) {
+	var event pullRequestEvent
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return event, fmt.Errorf("could not read pr_event_file: %w", err)
+	}
+	if err := json.Unmarshal(content, &event); err != nil {
+		return event, fmt.Errorf("could not parse pr_event_file %s: %w", path, err)
+	}
+	return event, nil
+}
+
+// truncateText limits text to max bytes without splitting a UTF-8 sequence
+func truncateText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && text[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return text[:cut] + "\n[truncated]"
+}
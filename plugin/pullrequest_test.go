@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	base := repo.commit("initial")
+	repo.write("a.txt", "a\n")
+	repo.commit("feat: add a\n\nExplains why a is needed.\nSecond line.")
+	repo.write("b.txt", "b\n")
+	head := repo.commit("fix b")
+
+	commits, err := ListCommits(repo.dir, base, head)
+	if err != nil {
+		t.Fatalf("ListCommits() failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	if commits[0].Subject != "feat: add a" || commits[0].Body != "Explains why a is needed.\nSecond line." {
+		t.Errorf("first commit = %+v, want oldest commit with body", commits[0])
+	}
+	if commits[1].SHA != head || commits[1].Author != "Test User" || commits[1].Body != "" {
+		t.Errorf("second commit = %+v, want head commit without body", commits[1])
+	}
+	if commits[0].IndentedBody() != "    Explains why a is needed.\n    Second line." {
+		t.Errorf("IndentedBody() = %q", commits[0].IndentedBody())
+	}
+}
+
+func TestCollectPullRequestInfo(t *testing.T) {
+	repo := newTestRepo(t)
+	base := repo.commit("initial")
+	repo.write("a.txt", "a\n")
+	head := repo.commit("add a")
+
+	dir := t.TempDir()
+	eventFile := filepath.Join(dir, "event.json")
+	event := `{"pull_request": {"title": "Event title", "body": "Event body"}}`
+	if err := os.WriteFile(eventFile, []byte(event), 0644); err != nil {
+		t.Fatalf("Failed to write event file: %v", err)
+	}
+	ticketFile := filepath.Join(dir, "ticket.txt")
+	if err := os.WriteFile(ticketFile, []byte("PROJ-1: users need a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ticket file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		settings     Settings
+		wantTitle    string
+		wantBody     string
+		wantTicket   string
+		wantCommits  int
+		wantWarnings int
+		wantNil      bool
+	}{
+		{
+			name:        "event payload fills missing fields",
+			settings:    Settings{PREventFile: eventFile, TicketFile: ticketFile, IncludeCommitLog: true},
+			wantTitle:   "Event title",
+			wantBody:    "Event body",
+			wantTicket:  "PROJ-1: users need a",
+			wantCommits: 1,
+		},
+		{
+			name:       "explicit settings win over the event payload",
+			settings:   Settings{PRTitle: "Setting title", PRDescription: "Setting body", PREventFile: eventFile, Ticket: "inline ticket"},
+			wantTitle:  "Setting title",
+			wantBody:   "Setting body",
+			wantTicket: "inline ticket",
+		},
+		{
+			name:         "missing files are warnings",
+			settings:     Settings{PRTitle: "Title", PRDescriptionFile: filepath.Join(dir, "missing.md"), TicketFile: filepath.Join(dir, "missing.txt")},
+			wantTitle:    "Title",
+			wantWarnings: 2,
+		},
+		{
+			name:     "nothing to report",
+			settings: Settings{},
+			wantNil:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.MergeBaseSha = base
+			tt.settings.SourceSha = head
+
+			info, warnings := CollectPullRequestInfo(repo.dir, tt.settings)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantNil {
+				if info != nil {
+					t.Errorf("info = %+v, want nil", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("info should not be nil")
+			}
+			if info.Title != tt.wantTitle || info.Description != tt.wantBody || info.Ticket != tt.wantTicket {
+				t.Errorf("info = %+v, want title %q body %q ticket %q", info, tt.wantTitle, tt.wantBody, tt.wantTicket)
+			}
+			if len(info.Commits) != tt.wantCommits {
+				t.Errorf("commits = %d, want %d", len(info.Commits), tt.wantCommits)
+			}
+		})
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("short", 10); got != "short" {
+		t.Errorf("truncateText() = %q, want unchanged text", got)
+	}
+	got := truncateText("héllo world", 2)
+	if !strings.HasPrefix(got, "h\n") || !strings.HasSuffix(got, "[truncated]") {
+		t.Errorf("truncateText() = %q, want cut before the multi-byte rune", got)
+	}
+}
@@ -0,0 +1,330 @@
+package plugin
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reportHTML is the self-contained page the HTML report is rendered into
+//
+//go:embed templates/report.html
+var reportHTML string
+
+// Report is a review output grouped by file and category, with the code the
+// comments refer to
+type Report struct {
+	SourceSha string
+	Comments  int
+	Counts    string
+	// Summary is the summary of the review output, nil when it has none
+	Summary *ReviewSummary
+	Files   []ReportFile
+}
+
+// ReportFile holds the comments on one file, grouped by category
+type ReportFile struct {
+	Path       string
+	Language   string
+	Categories []ReportCategory
+}
+
+// ReportCategory holds the comments of one type on a file
+type ReportCategory struct {
+	Type     string
+	Comments []ReportComment
+}
+
+// ReportComment is a review comment with its code and suggested changes
+type ReportComment struct {
+	ReviewComment
+	Text        string
+	Code        []CodeLine
+	Suggestions [][]DiffLine
+}
+
+// CodeLine is a numbered line of source code
+type CodeLine struct {
+	Number int
+	Text   string
+}
+
+// Lines describes the line range of the comment
+func (c ReportComment) Lines() string {
+	if c.LineNumberStart == c.LineNumberEnd {
+		return fmt.Sprintf("Line %d", c.LineNumberStart)
+	}
+	return fmt.Sprintf("Lines %d-%d", c.LineNumberStart, c.LineNumberEnd)
+}
+
+// Numbered returns the code of the comment with its line numbers
+func (c ReportComment) Numbered() string {
+	if len(c.Code) == 0 {
+		return ""
+	}
+	width := len(fmt.Sprint(c.Code[len(c.Code)-1].Number))
+	var b strings.Builder
+	for _, line := range c.Code {
+		fmt.Fprintf(&b, "%*d | %s\n", width, line.Number, line.Text)
+	}
+	return b.String()
+}
+
+// BuildReport groups the comments of output by file and category and reads the
+// lines they refer to from sha, or from the working tree when sha is empty.
+// Files keep the order of their first comment; categories are sorted by name.
+func BuildReport(dir, sha string, output ReviewOutput) *Report {
+	report := &Report{
+		SourceSha: sha,
+		Comments:  len(output.Reviews),
+		Counts:    typeCounts(output),
+	}
+	if s := output.Summary; s != nil && (s.Overview != "" || len(s.RiskAreas) > 0 || len(s.Walkthrough) > 0) {
+		report.Summary = s
+	}
+
+	index := make(map[string]int)
+	sources := make(map[string][]string)
+	for _, r := range output.Reviews {
+		i, ok := index[r.FilePath]
+		if !ok {
+			i = len(report.Files)
+			index[r.FilePath] = i
+			file := ReportFile{Path: r.FilePath}
+			if lang := DetectLanguage(r.FilePath, nil); lang != nil {
+				file.Language = lang.ID
+			}
+			report.Files = append(report.Files, file)
+			sources[r.FilePath] = sourceLines(dir, sha, r.FilePath)
+		}
+		file := &report.Files[i]
+
+		comment := ReportComment{ReviewComment: r}
+		comment.Code = codeLines(sources[r.FilePath], r.LineNumberStart, r.LineNumberEnd)
+		text, suggestions := ExtractSuggestions(r.Review)
+		comment.Text = text
+		before := make([]string, len(comment.Code))
+		for i, line := range comment.Code {
+			before[i] = line.Text
+		}
+		for _, s := range suggestions {
+			comment.Suggestions = append(comment.Suggestions, lineDiff(before, s, r.LineNumberStart))
+		}
+
+		category := categoryOf(file, r.Type)
+		category.Comments = append(category.Comments, comment)
+	}
+
+	for i := range report.Files {
+		sort.SliceStable(report.Files[i].Categories, func(a, b int) bool {
+			return report.Files[i].Categories[a].Type < report.Files[i].Categories[b].Type
+		})
+	}
+	return report
+}
+
+// categoryOf returns the category of a file for a comment type, adding it when missing
+func categoryOf(file *ReportFile, commentType string) *ReportCategory {
+	if commentType == "" {
+		commentType = "uncategorized"
+	}
+	for i := range file.Categories {
+		if file.Categories[i].Type == commentType {
+			return &file.Categories[i]
+		}
+	}
+	file.Categories = append(file.Categories, ReportCategory{Type: commentType})
+	return &file.Categories[len(file.Categories)-1]
+}
+
+// sourceLines reads a file at sha, or from the working tree when sha is empty.
+// Absolute paths and paths that leave dir read nothing.
+func sourceLines(dir, sha, filePath string) []string {
+	if !filepath.IsLocal(filepath.FromSlash(filePath)) {
+		return nil
+	}
+	var content string
+	if sha != "" {
+		content = fileAt(dir, sha, filePath)
+	} else if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(filePath))); err == nil {
+		content = string(data)
+	}
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// codeLines returns the numbered lines start to end of a file, or nil when
+// the range is not in the file
+func codeLines(lines []string, start, end int) []CodeLine {
+	if start < 1 || end > len(lines) || end < start {
+		return nil
+	}
+	var code []CodeLine
+	for n := start; n <= end; n++ {
+		code = append(code, CodeLine{Number: n, Text: lines[n-1]})
+	}
+	return code
+}
+
+// ExtractSuggestions removes the ```suggestion blocks from a review text and
+// returns the remaining text and the code of each block
+func ExtractSuggestions(review string) (string, [][]string) {
+	var text []string
+	var suggestions [][]string
+	var block []string
+	inBlock := false
+	for _, line := range strings.Split(review, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inBlock && strings.HasPrefix(trimmed, "```suggestion"):
+			inBlock = true
+			block = []string{}
+		case inBlock && trimmed == "```":
+			inBlock = false
+			suggestions = append(suggestions, block)
+		case inBlock:
+			block = append(block, line)
+		default:
+			text = append(text, line)
+		}
+	}
+	// An unterminated block is kept as text
+	if inBlock {
+		text = append(text, "```suggestion")
+		text = append(text, block...)
+	}
+	return strings.TrimSpace(strings.Join(text, "\n")), suggestions
+}
+
+// lineDiff returns the changes that turn before into after, keeping the lines
+// they share as context and removals before additions. Lines are numbered from
+// start on both sides.
+func lineDiff(before, after []string, start int) []DiffLine {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, DiffLine{Kind: ' ', OldLine: start + i, NewLine: start + j, Text: before[i]})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, DiffLine{Kind: '-', OldLine: start + i, Text: before[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Kind: '+', NewLine: start + j, Text: after[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// RenderReportMarkdown renders a report as a Markdown document
+func RenderReportMarkdown(report *Report) string {
+	var b strings.Builder
+	b.WriteString("# Review Report\n\n")
+	if report.SourceSha != "" {
+		fmt.Fprintf(&b, "Code from `%s`.\n\n", report.SourceSha)
+	}
+	if report.Comments == 0 {
+		b.WriteString("No comments.\n")
+	} else {
+		fmt.Fprintf(&b, "%d comments in %d files: %s.\n", report.Comments, len(report.Files), report.Counts)
+	}
+	if summary := report.Summary; summary != nil {
+		b.WriteString("\n## Summary\n")
+		if summary.Overview != "" {
+			b.WriteString("\n" + strings.TrimSpace(summary.Overview) + "\n")
+		}
+		if len(summary.RiskAreas) > 0 {
+			b.WriteString("\n### Risk Areas\n\n")
+			for _, risk := range summary.RiskAreas {
+				b.WriteString("- " + strings.Join(strings.Fields(risk), " ") + "\n")
+			}
+		}
+		if len(summary.Walkthrough) > 0 {
+			b.WriteString("\n### Walkthrough\n\n| File | Changes |\n|------|---------|\n")
+			for _, f := range summary.Walkthrough {
+				fmt.Fprintf(&b, "| `%s` | %s |\n", markdownCell(f.FilePath), markdownCell(f.Summary))
+			}
+		}
+	}
+
+	for _, file := range report.Files {
+		fmt.Fprintf(&b, "\n## `%s`\n", file.Path)
+		for _, category := range file.Categories {
+			fmt.Fprintf(&b, "\n### %s (%d)\n", category.Type, len(category.Comments))
+			for _, c := range category.Comments {
+				fmt.Fprintf(&b, "\n**%s**\n\n", c.Lines())
+				if code := c.Numbered(); code != "" {
+					fence := markdownFence(code)
+					fmt.Fprintf(&b, "%s%s\n%s%s\n\n", fence, file.Language, code, fence)
+				}
+				if c.Text != "" {
+					b.WriteString(c.Text + "\n")
+				}
+				for _, s := range c.Suggestions {
+					var diff strings.Builder
+					for _, line := range s {
+						diff.WriteString(string(line.Kind) + line.Text + "\n")
+					}
+					fence := markdownFence(diff.String())
+					fmt.Fprintf(&b, "\nSuggested change:\n\n%sdiff\n%s%s\n", fence, diff.String(), fence)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownFence returns a code fence longer than any run of backticks in
+// content, so code containing ``` cannot close the block early
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// RenderReportHTML renders a report as a self-contained HTML page
+func RenderReportHTML(report *Report) ([]byte, error) {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"kind": func(k byte) string { return string(k) },
+	}).Parse(reportHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
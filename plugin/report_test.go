@@ -0,0 +1,244 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractSuggestions(t *testing.T) {
+	review := "Check the error.\n```suggestion\nif err != nil {\n\treturn err\n}\n```\nOtherwise it is lost."
+	text, suggestions := ExtractSuggestions(review)
+	if want := "Check the error.\nOtherwise it is lost."; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if want := [][]string{{"if err != nil {", "\treturn err", "}"}}; !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("suggestions = %q, want %q", suggestions, want)
+	}
+
+	// An empty block deletes the lines; an unterminated block stays text
+	_, suggestions = ExtractSuggestions("Remove this.\n```suggestion\n```")
+	if !reflect.DeepEqual(suggestions, [][]string{{}}) {
+		t.Errorf("suggestions = %q, want one empty suggestion", suggestions)
+	}
+	text, suggestions = ExtractSuggestions("Broken\n```suggestion\nx := 1")
+	if suggestions != nil || text != "Broken\n```suggestion\nx := 1" {
+		t.Errorf("ExtractSuggestions() = %q, %q, want the unterminated block kept as text", text, suggestions)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	got := lineDiff([]string{"a", "b", "c"}, []string{"a", "B", "c", "d"}, 10)
+	want := []DiffLine{
+		{Kind: ' ', OldLine: 10, NewLine: 10, Text: "a"},
+		{Kind: '-', OldLine: 11, Text: "b"},
+		{Kind: '+', NewLine: 11, Text: "B"},
+		{Kind: ' ', OldLine: 12, NewLine: 12, Text: "c"},
+		{Kind: '+', NewLine: 13, Text: "d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lineDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// reportOutput returns review output with comments on two files
+func reportOutput() ReviewOutput {
+	return ReviewOutput{
+		Reviews: []ReviewComment{
+			{FilePath: "main.go", LineNumberStart: 4, LineNumberEnd: 4, Type: "performance", Review: "Allocates in a loop."},
+			{FilePath: "README.md", LineNumberStart: 1, LineNumberEnd: 1, Type: "code_smell", Review: "Typo."},
+			{FilePath: "main.go", LineNumberStart: 3, LineNumberEnd: 5, Type: "bug", Review: "Ignores the error.\n```suggestion\nfunc main() {\n\tif err := run(); err != nil {\n\t\tpanic(err)\n\t}\n}\n```"},
+		},
+		Summary: &ReviewSummary{
+			Overview:    "Adds a <run> helper.",
+			RiskAreas:   []string{"Panics on <error>."},
+			Walkthrough: []FileSummary{{FilePath: "main.go", Summary: "Calls run."}},
+		},
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n\nfunc main() {\n\trun()\n}\n")
+	repo.write("README.md", "# Tset\n")
+	sha := repo.commit("initial")
+	repo.write("main.go", "package main\n")
+
+	report := BuildReport(repo.dir, sha, reportOutput())
+	if report.Comments != 3 || report.Counts != "1 bug, 1 code_smell, 1 performance" {
+		t.Errorf("Comments = %d, Counts = %q", report.Comments, report.Counts)
+	}
+
+	var files, categories []string
+	for _, f := range report.Files {
+		files = append(files, f.Path)
+		for _, c := range f.Categories {
+			categories = append(categories, f.Path+":"+c.Type)
+		}
+	}
+	if want := []string{"main.go", "README.md"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Files = %v, want %v in order of their first comment", files, want)
+	}
+	if want := []string{"main.go:bug", "main.go:performance", "README.md:code_smell"}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("Categories = %v, want %v", categories, want)
+	}
+	if report.Files[0].Language != "go" {
+		t.Errorf("Language = %q, want go", report.Files[0].Language)
+	}
+
+	bug := report.Files[0].Categories[0].Comments[0]
+	wantCode := []CodeLine{{3, "func main() {"}, {4, "\trun()"}, {5, "}"}}
+	if !reflect.DeepEqual(bug.Code, wantCode) {
+		t.Errorf("Code = %q, want the lines at %s", bug.Code, sha)
+	}
+	if bug.Text != "Ignores the error." || len(bug.Suggestions) != 1 {
+		t.Fatalf("Text = %q with %d suggestions", bug.Text, len(bug.Suggestions))
+	}
+	var kinds string
+	for _, line := range bug.Suggestions[0] {
+		kinds += string(line.Kind)
+	}
+	if kinds != " -+++ " {
+		t.Errorf("Suggestion kinds = %q, want %q", kinds, " -+++ ")
+	}
+
+	// Without a SHA the working tree is read; out-of-range comments have no code
+	report = BuildReport(repo.dir, "", reportOutput())
+	if code := report.Files[0].Categories[1].Comments[0].Code; code != nil {
+		t.Errorf("Code = %q, want none for a line past the end of the working tree file", code)
+	}
+
+	// Paths outside the repository are never read
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("token\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	relative, err := filepath.Rel(repo.dir, outside)
+	if err != nil {
+		t.Fatalf("filepath.Rel() failed: %v", err)
+	}
+	for _, path := range []string{filepath.ToSlash(relative), filepath.ToSlash(outside)} {
+		output := ReviewOutput{Reviews: []ReviewComment{{FilePath: path, LineNumberStart: 1, LineNumberEnd: 1, Type: "bug", Review: "Leak."}}}
+		if code := BuildReport(repo.dir, "", output).Files[0].Categories[0].Comments[0].Code; code != nil {
+			t.Errorf("Code = %q, want none for %s", code, path)
+		}
+	}
+
+	// An empty summary is left out
+	output := reportOutput()
+	output.Summary = &ReviewSummary{}
+	if report := BuildReport(repo.dir, sha, output); report.Summary != nil {
+		t.Errorf("Summary = %+v, want nil for an empty summary", report.Summary)
+	}
+}
+
+func TestRenderReportMarkdown(t *testing.T) {
+	report := &Report{
+		SourceSha: "abc123",
+		Comments:  1,
+		Counts:    "1 bug",
+		Summary:   &ReviewSummary{Overview: "Runs the job.", RiskAreas: []string{"Panics on\nerror."}, Walkthrough: []FileSummary{{FilePath: "main.go", Summary: "Calls | run."}}},
+		Files: []ReportFile{{Path: "main.go", Language: "go", Categories: []ReportCategory{{
+			Type: "bug",
+			Comments: []ReportComment{{
+				ReviewComment: ReviewComment{LineNumberStart: 9, LineNumberEnd: 10},
+				Text:          "Ignores the error.",
+				Code:          []CodeLine{{9, "run()"}, {10, "}"}},
+				Suggestions:   [][]DiffLine{{{Kind: '-', Text: "run()"}, {Kind: '+', Text: "must(run())"}, {Kind: ' ', Text: "}"}}},
+			}},
+		}}}},
+	}
+	want := "# Review Report\n\n" +
+		"Code from `abc123`.\n\n" +
+		"1 comments in 1 files: 1 bug.\n" +
+		"\n## Summary\n\nRuns the job.\n" +
+		"\n### Risk Areas\n\n- Panics on error.\n" +
+		"\n### Walkthrough\n\n| File | Changes |\n|------|---------|\n| `main.go` | Calls \\| run. |\n" +
+		"\n## `main.go`\n" +
+		"\n### bug (1)\n" +
+		"\n**Lines 9-10**\n\n" +
+		"```go\n 9 | run()\n10 | }\n```\n\n" +
+		"Ignores the error.\n" +
+		"\nSuggested change:\n\n```diff\n-run()\n+must(run())\n }\n```\n"
+	if got := RenderReportMarkdown(report); got != want {
+		t.Errorf("RenderReportMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := RenderReportMarkdown(&Report{}); got != "# Review Report\n\nNo comments.\n" {
+		t.Errorf("RenderReportMarkdown() = %q for an empty report", got)
+	}
+
+	// Code containing backticks gets a longer fence
+	report.Summary = nil
+	comment := &report.Files[0].Categories[0].Comments[0]
+	comment.Code = []CodeLine{{9, "s := \"```go\""}}
+	comment.Suggestions = [][]DiffLine{{{Kind: '+', Text: "s := \"````\""}}}
+	got := RenderReportMarkdown(report)
+	for _, expected := range []string{
+		"````go\n9 | s := \"```go\"\n````\n\n",
+		"\n`````diff\n+s := \"````\"\n`````\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("RenderReportMarkdown() should contain %q, got:\n%s", expected, got)
+		}
+	}
+}
+
+func TestRenderReportHTML(t *testing.T) {
+	report := BuildReport(t.TempDir(), "", reportOutput())
+	page, err := RenderReportHTML(report)
+	if err != nil {
+		t.Fatalf("RenderReportHTML() failed: %v", err)
+	}
+	html := string(page)
+	for _, expected := range []string{
+		"<h2>main.go</h2>",
+		`<span class="type">bug</span>`,
+		"Adds a &lt;run&gt; helper.",
+		"<li>Panics on &lt;error&gt;.</li>",
+		"<tr><td><code>main.go</code></td><td>Calls run.</td></tr>",
+		`<tr class="add">`,
+		"Lines 3-5",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("HTML report should contain %q", expected)
+		}
+	}
+	for _, external := range []string{"<link", "<script", "src="} {
+		if strings.Contains(html, external) {
+			t.Errorf("HTML report should be self-contained, found %q", external)
+		}
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	os.Clearenv()
+	tempDir := t.TempDir()
+	reviewFile := filepath.Join(tempDir, "review.json")
+	markdownFile := filepath.Join(tempDir, "review.md")
+	review := `{"reviews": [{"file_path": "missing.go", "line_number_start": 1, "line_number_end": 1, "type": "bug", "review": "Nil map write."}]}`
+	if err := os.WriteFile(reviewFile, []byte(review), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	args := []string{"report", "-review-output-file", reviewFile, "-report-markdown-file", markdownFile, "-report-html-file", ""}
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Successfully generated Markdown report at: "+markdownFile) || strings.Contains(stdout.String(), "HTML") {
+		t.Errorf("report should only write the Markdown report, got:\n%s", stdout.String())
+	}
+	content, err := os.ReadFile(markdownFile)
+	if err != nil {
+		t.Fatalf("Failed to read Markdown report: %v", err)
+	}
+	if !strings.Contains(string(content), "## `missing.go`\n\n### bug (1)\n\n**Line 1**\n\nNil map write.\n") {
+		t.Errorf("Markdown report should contain the comment, got:\n%s", content)
+	}
+	if code := Run(append(args, "-no-overwrite"), &stdout, &stderr); code != 1 {
+		t.Errorf("Run() = %d, want 1 for an existing report with no_overwrite", code)
+	}
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ReviewOutput is the JSON document the AI model writes to ReviewOutputFile
+type ReviewOutput struct {
+	Reviews []ReviewComment `json:"reviews"`
+	Summary *ReviewSummary  `json:"summary,omitempty"`
+}
+
+// ReviewComment is a single review comment on a range of changed lines
+type ReviewComment struct {
+	FilePath        string `json:"file_path"`
+	LineNumberStart int    `json:"line_number_start"`
+	LineNumberEnd   int    `json:"line_number_end"`
+	Type            string `json:"type"`
+	Review          string `json:"review"`
+	// SuggestionCheck is recorded by publish when it verifies the suggestion blocks
+	SuggestionCheck *SuggestionCheck `json:"suggestion_check,omitempty"`
+}
+
+// ReviewSummary is the optional top-level description of the pull request
+type ReviewSummary struct {
+	Overview    string        `json:"overview"`
+	RiskAreas   []string      `json:"risk_areas,omitempty"`
+	Walkthrough []FileSummary `json:"walkthrough,omitempty"`
+}
+
+// FileSummary describes what changed in one file of the pull request
+type FileSummary struct {
+	FilePath string `json:"file_path"`
+	Summary  string `json:"summary"`
+}
+
+// ReadReviewOutput loads and decodes a review output file
+func ReadReviewOutput(path string) (ReviewOutput, error) {
+	var output ReviewOutput
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return output, fmt.Errorf("failed to read review output: %w", err)
+	}
+	if err := json.Unmarshal(content, &output); err != nil {
+		return output, fmt.Errorf("failed to parse review output %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// WriteReviewOutput encodes output as indented JSON and atomically replaces path
+func WriteReviewOutput(path string, output ReviewOutput, mode os.FileMode) error {
+	data, err := json.MarshalIndent(output, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode review output: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), mode, true); err != nil {
+		return fmt.Errorf("failed to write review output: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that every comment references a file and a sensible line range
+func (o ReviewOutput) Validate() error {
+	var errs []error
+	for i, r := range o.Reviews {
+		if r.FilePath == "" {
+			errs = append(errs, fmt.Errorf("review %d: file_path is required", i))
+		}
+		if r.LineNumberStart < 0 || r.LineNumberEnd < r.LineNumberStart {
+			errs = append(errs, fmt.Errorf("review %d: invalid line range %d-%d", i, r.LineNumberStart, r.LineNumberEnd))
+		}
+		if r.Review == "" {
+			errs = append(errs, fmt.Errorf("review %d: review text is required", i))
+		}
+	}
+	if o.Summary != nil {
+		for i, f := range o.Summary.Walkthrough {
+			if f.FilePath == "" {
+				errs = append(errs, fmt.Errorf("summary walkthrough %d: file_path is required", i))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AddComments appends comments to the review output, skipping those already
+// present with the same location, type and text, and returns how many it added
+func (o *ReviewOutput) AddComments(comments []ReviewComment) int {
+	seen := make(map[ReviewComment]bool)
+	for _, r := range o.Reviews {
+		seen[r] = true
+	}
+
+	added := 0
+	for _, comment := range comments {
+		if seen[comment] {
+			continue
+		}
+		seen[comment] = true
+		o.Reviews = append(o.Reviews, comment)
+		added++
+	}
+	return added
+}
+
+// CountByType returns the number of comments per review type
+func (o ReviewOutput) CountByType() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range o.Reviews {
+		counts[r.Type]++
+	}
+	return counts
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadReviewOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.json")
+	content := `{"reviews": [{"file_path": "a.go", "line_number_start": 1, "line_number_end": 2, "type": "bug", "review": "x"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	output, err := ReadReviewOutput(path)
+	if err != nil {
+		t.Fatalf("ReadReviewOutput() failed: %v", err)
+	}
+	if len(output.Reviews) != 1 || output.Reviews[0].FilePath != "a.go" || output.Reviews[0].LineNumberEnd != 2 {
+		t.Errorf("Reviews = %+v, want one comment on a.go lines 1-2", output.Reviews)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+	if _, err := ReadReviewOutput(path); err == nil {
+		t.Error("ReadReviewOutput() should fail for invalid JSON")
+	}
+	if _, err := ReadReviewOutput(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("ReadReviewOutput() should fail for a missing file")
+	}
+}
+
+func TestReviewOutputValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		review    ReviewComment
+		wantError bool
+	}{
+		{"valid", ReviewComment{FilePath: "a.go", LineNumberStart: 1, LineNumberEnd: 3, Type: "bug", Review: "x"}, false},
+		{"missing file", ReviewComment{LineNumberStart: 1, LineNumberEnd: 1, Review: "x"}, true},
+		{"reversed range", ReviewComment{FilePath: "a.go", LineNumberStart: 5, LineNumberEnd: 2, Review: "x"}, true},
+		{"empty review", ReviewComment{FilePath: "a.go", LineNumberStart: 1, LineNumberEnd: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReviewOutput{Reviews: []ReviewComment{tt.review}}.Validate()
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validate() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCountByType(t *testing.T) {
+	output := ReviewOutput{Reviews: []ReviewComment{{Type: "bug"}, {Type: "bug"}, {Type: "performance"}}}
+	counts := output.CountByType()
+	if counts["bug"] != 2 || counts["performance"] != 1 {
+		t.Errorf("CountByType() = %v, want bug:2 performance:1", counts)
+	}
+}
@@ -0,0 +1,221 @@
+package plugin
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Risk levels derived from a file's score
+const (
+	riskHigh   = "high"
+	riskMedium = "medium"
+	riskLow    = "low"
+)
+
+// maxOverviewRows bounds the table in the prompt; the manifest keeps every file
+const maxOverviewRows = 40
+
+// FileRisk is the overview row of a changed file
+type FileRisk struct {
+	Path      string   `json:"path"`
+	Status    string   `json:"status"`
+	Type      string   `json:"type"`
+	Additions int      `json:"additions"`
+	Deletions int      `json:"deletions"`
+	Churn     int      `json:"churn"`
+	Test      bool     `json:"test,omitempty"`
+	Score     int      `json:"risk_score"`
+	Level     string   `json:"risk_level"`
+	Reasons   []string `json:"risk_reasons,omitempty"`
+}
+
+// DiffOverview summarizes the shape of the change, riskiest files first
+type DiffOverview struct {
+	Stats         DiffStats  `json:"-"`
+	TestsChanged  bool       `json:"tests_changed"`
+	Files         []FileRisk `json:"files"`
+	HighRiskFiles int        `json:"high_risk_files"`
+
+	// OmittedFiles and OmittedChurn count the files beyond the prompt table
+	OmittedFiles int `json:"-"`
+	OmittedChurn int `json:"-"`
+}
+
+// riskSignal raises the score of files whose path or changed lines match
+type riskSignal struct {
+	reason  string
+	score   int
+	path    *regexp.Regexp
+	content *regexp.Regexp
+}
+
+// riskSignals are the built-in indicators of code that deserves extra scrutiny
+var riskSignals = []riskSignal{
+	{
+		reason: "touches authentication or security code",
+		score:  3,
+		path:   regexp.MustCompile(`(?i)(^|[/_.-])(auth|authn|authz|authentication|authorization|login|logout|sessions?|passwords?|credentials?|permissions?|rbac|acl|oauth2?|jwt|crypto|secrets?|tokens?)([/_.-]|$)`),
+	},
+	{
+		reason: "touches database migrations",
+		score:  3,
+		path:   regexp.MustCompile(`(?i)(^|/)(migrations?|migrate|db/schema)(/|$|\.)`),
+	},
+	{
+		reason:  "changes database schema",
+		score:   2,
+		content: regexp.MustCompile(`(?i)\b(alter|drop|truncate)\s+(table|column|index)\b`),
+	},
+	{
+		reason:  "changes concurrency primitives",
+		score:   3,
+		content: regexp.MustCompile(`\bsync\.(Mutex|RWMutex|WaitGroup|Once|Cond|Map)\b|\bgo func\b|\bchan\b|\batomic\.|\.(R?Lock|R?Unlock)\(\)|\bsynchronized\b|\bthreading\.|\basyncio\.|\bSemaphore\b|\bConcurrentHashMap\b|\bArc<|\bMutex<`),
+	},
+}
+
+// testPathPattern matches the conventional locations and names of test files
+var testPathPattern = regexp.MustCompile(`(^|/)(tests?|__tests__|spec)/|_test\.go$|\.(test|spec)\.[a-z]+$|(^|/)test_[^/]*\.py$|_test\.py$|Tests?\.(java|kt|cs)$|_spec\.rb$`)
+
+// isTestFile reports whether p looks like a test file
+func isTestFile(p string) bool {
+	return testPathPattern.MatchString(p)
+}
+
+// isSourceFile reports whether p is program source that tests are expected to cover
+func isSourceFile(p string) bool {
+	lang := DetectLanguage(p, nil)
+	if lang == nil {
+		return false
+	}
+	switch lang.ID {
+	case "sql", "terraform", "dockerfile":
+		return false
+	}
+	return !isTestFile(p)
+}
+
+// fileType names the kind of a changed file for the overview table
+func fileType(f ChangedFile) string {
+	if f.Binary {
+		return "binary"
+	}
+	if lang := DetectLanguage(f.Path, nil); lang != nil {
+		return lang.Name
+	}
+	if ext := path.Ext(f.Path); ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	return "-"
+}
+
+// AnalyzeRisk scores each changed file using its size, path and changed lines and
+// returns the files ordered from riskiest to least risky
+func AnalyzeRisk(files []ChangedFile, diffs []FileDiff) *DiffOverview {
+	changed := make(map[string][]string)
+	for _, d := range diffs {
+		changed[d.Path] = d.ChangedText()
+	}
+
+	overview := &DiffOverview{Stats: SummarizeDiff(files)}
+	for _, f := range files {
+		if isTestFile(f.Path) {
+			overview.TestsChanged = true
+		}
+	}
+
+	for _, f := range files {
+		r := FileRisk{
+			Path:      f.Path,
+			Status:    f.Status,
+			Type:      fileType(f),
+			Additions: f.Additions,
+			Deletions: f.Deletions,
+			Churn:     f.Additions + f.Deletions,
+			Test:      isTestFile(f.Path),
+		}
+		addReason := func(score int, reason string) {
+			r.Score += score
+			r.Reasons = append(r.Reasons, reason)
+		}
+
+		switch {
+		case r.Churn >= 500:
+			addReason(3, fmt.Sprintf("large change (%d lines)", r.Churn))
+		case r.Churn >= 200:
+			addReason(2, fmt.Sprintf("large change (%d lines)", r.Churn))
+		}
+
+		if f.Status != "D" {
+			for _, signal := range riskSignals {
+				if signal.path != nil && signal.path.MatchString(f.Path) || signal.content != nil && matchesAny(signal.content, changed[f.Path]) {
+					addReason(signal.score, signal.reason)
+				}
+			}
+		}
+
+		if !overview.TestsChanged && f.Status != "D" && isSourceFile(f.Path) {
+			addReason(1, "source changed without any test changes")
+		}
+
+		r.Level = riskLevel(r.Score)
+		if r.Level == riskHigh {
+			overview.HighRiskFiles++
+		}
+		overview.Files = append(overview.Files, r)
+	}
+
+	sort.SliceStable(overview.Files, func(i, j int) bool {
+		if overview.Files[i].Score != overview.Files[j].Score {
+			return overview.Files[i].Score > overview.Files[j].Score
+		}
+		return overview.Files[i].Churn > overview.Files[j].Churn
+	})
+	if len(overview.Files) > maxOverviewRows {
+		for _, f := range overview.Files[maxOverviewRows:] {
+			overview.OmittedFiles++
+			overview.OmittedChurn += f.Churn
+		}
+	}
+	return overview
+}
+
+// riskLevel buckets a risk score
+func riskLevel(score int) string {
+	switch {
+	case score >= 5:
+		return riskHigh
+	case score >= 3:
+		return riskMedium
+	default:
+		return riskLow
+	}
+}
+
+// matchesAny reports whether pattern matches any of lines
+func matchesAny(pattern *regexp.Regexp, lines []string) bool {
+	for _, line := range lines {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rows returns the riskiest files, which are shown in the prompt table
+func (o DiffOverview) Rows() []FileRisk {
+	if len(o.Files) > maxOverviewRows {
+		return o.Files[:maxOverviewRows]
+	}
+	return o.Files
+}
+
+// ReasonList joins the risk reasons for the table, or "-" when there are none
+func (r FileRisk) ReasonList() string {
+	if len(r.Reasons) == 0 {
+		return "-"
+	}
+	return strings.Join(r.Reasons, "; ")
+}
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeRisk(t *testing.T) {
+	files := []ChangedFile{
+		{Path: "README.md", Status: "M", Additions: 2, Deletions: 1},
+		{Path: "internal/auth/session.go", Status: "M", Additions: 10, Deletions: 4},
+		{Path: "db/migrations/0002_users.sql", Status: "A", Additions: 3},
+		{Path: "worker/pool.go", Status: "M", Additions: 300, Deletions: 20},
+		{Path: "logo.png", Status: "A", Binary: true},
+	}
+	diffs := []FileDiff{
+		{Path: "db/migrations/0002_users.sql", Hunks: []Hunk{{Lines: []DiffLine{{Kind: '+', NewLine: 1, Text: "ALTER TABLE users DROP COLUMN name;"}}}}},
+		{Path: "worker/pool.go", Hunks: []Hunk{{Lines: []DiffLine{{Kind: '+', NewLine: 7, Text: "\tvar mu sync.Mutex"}}}}},
+	}
+
+	overview := AnalyzeRisk(files, diffs)
+	if overview.Stats != (DiffStats{Files: 5, Additions: 315, Deletions: 25}) {
+		t.Errorf("Stats = %+v, want 5 files +315 -25", overview.Stats)
+	}
+	if overview.TestsChanged {
+		t.Error("TestsChanged should be false without test files")
+	}
+
+	var order []string
+	byPath := make(map[string]FileRisk)
+	for _, f := range overview.Files {
+		order = append(order, f.Path)
+		byPath[f.Path] = f
+	}
+	want := []string{"worker/pool.go", "db/migrations/0002_users.sql", "internal/auth/session.go", "README.md", "logo.png"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	pool := byPath["worker/pool.go"]
+	if pool.Level != riskHigh || pool.Churn != 320 || pool.Type != "Go" {
+		t.Errorf("worker/pool.go = %+v, want high risk Go file with churn 320", pool)
+	}
+	for _, reason := range []string{"large change (320 lines)", "changes concurrency primitives", "source changed without any test changes"} {
+		if !strings.Contains(pool.ReasonList(), reason) {
+			t.Errorf("worker/pool.go reasons %q should contain %q", pool.ReasonList(), reason)
+		}
+	}
+	if m := byPath["db/migrations/0002_users.sql"]; m.Level != riskHigh || len(m.Reasons) != 2 {
+		t.Errorf("migration = %+v, want high risk from the path and the schema change", m)
+	}
+	if a := byPath["internal/auth/session.go"]; a.Level != riskMedium {
+		t.Errorf("auth file = %+v, want medium risk", a)
+	}
+	if r := byPath["README.md"]; r.Score != 0 || r.ReasonList() != "-" || r.Type != "md" {
+		t.Errorf("README.md = %+v, want no risk", r)
+	}
+	if b := byPath["logo.png"]; b.Type != "binary" {
+		t.Errorf("logo.png type = %q, want binary", b.Type)
+	}
+	if overview.HighRiskFiles != 2 {
+		t.Errorf("HighRiskFiles = %d, want 2", overview.HighRiskFiles)
+	}
+}
+
+func TestAnalyzeRiskWithTests(t *testing.T) {
+	files := []ChangedFile{
+		{Path: "pkg/tokenizer.go", Status: "M", Additions: 5},
+		{Path: "pkg/tokenizer_test.go", Status: "M", Additions: 9},
+	}
+	overview := AnalyzeRisk(files, nil)
+	if !overview.TestsChanged {
+		t.Error("TestsChanged should be true when a test file changed")
+	}
+	for _, f := range overview.Files {
+		if f.Score != 0 {
+			t.Errorf("%s = %+v, want no risk", f.Path, f)
+		}
+	}
+}
+
+func TestAnalyzeRiskOmitsRows(t *testing.T) {
+	var files []ChangedFile
+	for i := 0; i < maxOverviewRows+3; i++ {
+		files = append(files, ChangedFile{Path: fmt.Sprintf("docs/page%d.md", i), Status: "M", Additions: 2})
+	}
+	overview := AnalyzeRisk(files, nil)
+	if len(overview.Rows()) != maxOverviewRows || overview.OmittedFiles != 3 || overview.OmittedChurn != 6 {
+		t.Errorf("Rows() = %d rows, omitted %d files with %d lines; want %d rows, 3 files, 6 lines",
+			len(overview.Rows()), overview.OmittedFiles, overview.OmittedChurn, maxOverviewRows)
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	tests := map[string]bool{
+		"plugin/risk_test.go":        true,
+		"src/app.test.ts":            true,
+		"src/app.spec.js":            true,
+		"tests/test_app.py":          true,
+		"app/test_views.py":          true,
+		"src/__tests__/app.js":       true,
+		"src/test/java/AppTest.java": true,
+		"spec/models/user_spec.rb":   true,
+		"plugin/risk.go":             false,
+		"src/latest.ts":              false,
+		"contest.py":                 false,
+	}
+	for path, want := range tests {
+		if got := isTestFile(path); got != want {
+			t.Errorf("isTestFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -1,51 +1,292 @@
 package plugin
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
-// Settings defines the plugin input parameters
+// StdoutPath is the OutputFile value that streams the prompt to standard output
+const StdoutPath = "-"
+
+// Settings defines the plugin input parameters.
+//
+// Each field's tags are the single source of truth for how it is configured:
+// json is the setting name used in .drone.yml (and, dashed, as the CLI flag),
+// env is the PLUGIN_* variable Drone derives from it, fallback is an optional
+// CI variable consulted when env is unset, default is the value used when
+// neither is set, and help describes the setting.
 type Settings struct {
 	// Git repository information
-	RepoName     string
-	SourceBranch string
-	TargetBranch string
-	MergeBaseSha string
-	SourceSha    string
+	RepoName     string `json:"repo_name" env:"PLUGIN_REPO_NAME" fallback:"DRONE_REPO_NAME" help:"Repository name"`
+	SourceBranch string `json:"source_branch" env:"PLUGIN_SOURCE_BRANCH" fallback:"DRONE_SOURCE_BRANCH" help:"Source branch of the PR"`
+	TargetBranch string `json:"target_branch" env:"PLUGIN_TARGET_BRANCH" fallback:"DRONE_TARGET_BRANCH" help:"Target branch of the PR"`
+	MergeBaseSha string `json:"merge_base_sha" env:"PLUGIN_MERGE_BASE_SHA" fallback:"DRONE_COMMIT_BEFORE" help:"Merge base SHA for diff comparison"`
+	SourceSha    string `json:"source_sha" env:"PLUGIN_SOURCE_SHA" fallback:"DRONE_COMMIT_SHA" help:"Source commit SHA for diff comparison"`
 
 	// Review type flags (all enabled by default)
-	EnableBugs        bool
-	EnablePerformance bool
-	EnableScalability bool
-	EnableCodeSmell   bool
+	EnableBugs        bool `json:"enable_bugs" env:"PLUGIN_ENABLE_BUGS" default:"true" help:"Enable bug detection"`
+	EnablePerformance bool `json:"enable_performance" env:"PLUGIN_ENABLE_PERFORMANCE" default:"true" help:"Enable performance reviews"`
+	EnableScalability bool `json:"enable_scalability" env:"PLUGIN_ENABLE_SCALABILITY" default:"true" help:"Enable scalability reviews"`
+	EnableCodeSmell   bool `json:"enable_code_smell" env:"PLUGIN_ENABLE_CODE_SMELL" default:"true" help:"Enable code smell detection"`
 
 	// Review configuration
-	CommentCount     int
-	OutputFile       string
-	ReviewOutputFile string
-	CustomRulesPath  string
+	CommentCount     int    `json:"comment_count" env:"PLUGIN_COMMENT_COUNT" default:"10" help:"Maximum comments per PR"`
+	OutputFile       string `json:"output_file" env:"PLUGIN_OUTPUT_FILE" default:"../output/task.txt" help:"Path where the prompt file is written, or - for stdout"`
+	ReviewOutputFile string `json:"review_output_file" env:"PLUGIN_REVIEW_OUTPUT_FILE" default:"../output/review.json" help:"Path where the AI should write the review output"`
+	CustomRulesPath  string `json:"custom_rules_path" env:"PLUGIN_CUSTOM_RULES_PATH" default:".harness/rules/review.md" secret:"true" help:"Custom rules file path"`
+	PromptTemplate   string `json:"prompt_template" env:"PLUGIN_PROMPT_TEMPLATE" default:"v1" help:"Prompt version to render: v1, v2-strict or v2-lenient"`
+	PromptLanguage   string `json:"prompt_language" env:"PLUGIN_PROMPT_LANGUAGE" default:"en" help:"Language of the prompt instructions and review comments: en, de or ja"`
+	ManifestFile     string `json:"manifest_file" env:"PLUGIN_MANIFEST_FILE" help:"Path where the run manifest is written (default: manifest.json next to output_file)"`
+
+	// Output file handling
+	FileMode       string `json:"file_mode" env:"PLUGIN_FILE_MODE" default:"0644" help:"Octal permissions for generated files"`
+	NoOverwrite    bool   `json:"no_overwrite" env:"PLUGIN_NO_OVERWRITE" default:"false" help:"Fail instead of replacing existing output files"`
+	DryRun         bool   `json:"dry_run" env:"PLUGIN_DRY_RUN" default:"false" help:"Print the resolved settings and rendered prompt without writing files"`
+	OutputVarsFile string `json:"output_vars_file" env:"PLUGIN_OUTPUT_VARS_FILE" fallback:"DRONE_OUTPUT" secret:"true" help:"File that receives step output variables from the publish command"`
+
+	// Change overview
+	EnableDiffOverview bool `json:"enable_diff_overview" env:"PLUGIN_ENABLE_DIFF_OVERVIEW" default:"true" help:"Start the prompt with a table of changed files, their size and risk"`
+
+	// Missing test detection
+	EnableMissingTests bool   `json:"enable_missing_tests" env:"PLUGIN_ENABLE_MISSING_TESTS" default:"true" help:"List changed source files whose conventional test files did not change"`
+	TestPatterns       string `json:"test_patterns" env:"PLUGIN_TEST_PATTERNS" help:"Extra comma-separated .ext=template test file conventions, e.g. .py=tests/unit/test_{name}.py"`
+	EnableTestCoverage bool   `json:"enable_test_coverage" env:"PLUGIN_ENABLE_TEST_COVERAGE" default:"false" help:"Enable test_coverage findings for changed behavior that no test covers"`
+
+	// Coverage report
+	CoverageFile   string `json:"coverage_file" env:"PLUGIN_COVERAGE_FILE" help:"Coverage report used to point the model at changed lines no test executed"`
+	CoverageFormat string `json:"coverage_format" env:"PLUGIN_COVERAGE_FORMAT" default:"auto" help:"Format of coverage_file: auto, go, lcov or cobertura"`
+
+	// Static analysis reports
+	ToolReports       string `json:"tool_reports" env:"PLUGIN_TOOL_REPORTS" help:"Comma-separated SARIF or checkstyle reports whose findings on changed lines the model should not repeat"`
+	MergeToolFindings bool   `json:"merge_tool_findings" env:"PLUGIN_MERGE_TOOL_FINDINGS" default:"false" help:"Have the publish command add the tool findings on changed lines to the review output"`
+
+	// Dependency manifests
+	EnableDependencyReview bool `json:"enable_dependency_review" env:"PLUGIN_ENABLE_DEPENDENCY_REVIEW" default:"true" help:"List dependencies added, removed or upgraded in go.mod, package.json, requirements.txt and Cargo.toml"`
+
+	// Database migrations
+	EnableMigrationReview bool   `json:"enable_migration_review" env:"PLUGIN_ENABLE_MIGRATION_REVIEW" default:"true" help:"Add a migration checklist and the migration category when the change includes database migrations"`
+	MigrationGlobs        string `json:"migration_globs" env:"PLUGIN_MIGRATION_GLOBS" help:"Extra comma-separated globs of migration files; ** matches any number of directories"`
+
+	// Go API compatibility
+	EnableAPICompat bool `json:"enable_api_compat" env:"PLUGIN_ENABLE_API_COMPAT" default:"true" help:"List exported Go functions, types, methods and struct fields removed or changed between the two commits"`
+
+	// Enclosing function context
+	EnableFunctionContext   bool `json:"enable_function_context" env:"PLUGIN_ENABLE_FUNCTION_CONTEXT" default:"true" help:"Embed the function, method or class enclosing each changed hunk with its line numbers"`
+	FunctionContextMaxLines int  `json:"function_context_max_lines" env:"PLUGIN_FUNCTION_CONTEXT_MAX_LINES" default:"200" help:"Maximum lines of enclosing functions embedded per file, or 0 for no limit"`
+
+	// Caller context
+	EnableCallerContext bool `json:"enable_caller_context" env:"PLUGIN_ENABLE_CALLER_CONTEXT" default:"true" help:"List call sites across the module of the Go functions changed by the diff"`
+
+	// Token budget
+	Tokenizer string `json:"tokenizer" env:"PLUGIN_TOKENIZER" default:"bpe" help:"Token estimator for the size report: bpe (approximates current BPE tokenizers) or chars (four characters per token)"`
+	MaxTokens int    `json:"max_tokens" env:"PLUGIN_MAX_TOKENS" default:"0" help:"Token budget for the prompt and diff; lower-risk files and then context sections are omitted to fit, or 0 for no limit"`
+
+	// Review summary
+	EnableSummary bool   `json:"enable_summary" env:"PLUGIN_ENABLE_SUMMARY" default:"false" help:"Ask for a summary of the pull request, its risk areas and a file-by-file walkthrough next to the comments"`
+	SummaryFile   string `json:"summary_file" env:"PLUGIN_SUMMARY_FILE" default:"-" help:"Path where the summary command writes the Markdown pull request summary, or - for stdout"`
+
+	// Review profile
+	Profile      string `json:"profile" env:"PLUGIN_PROFILE" help:"Review profile bundling tone, strictness, categories, comment count and rules: security-auditor, mentor, bugs-only or a profile in profiles_path"`
+	ProfilesPath string `json:"profiles_path" env:"PLUGIN_PROFILES_PATH" default:".harness/rules/profiles" help:"Directory of <name>.json review profiles that add to or replace the built-in ones"`
+
+	// Review report
+	ReportMarkdownFile string `json:"report_markdown_file" env:"PLUGIN_REPORT_MARKDOWN_FILE" default:"../output/review.md" help:"Path where the report command writes the Markdown report, or empty to skip it"`
+	ReportHTMLFile     string `json:"report_html_file" env:"PLUGIN_REPORT_HTML_FILE" default:"../output/review.html" help:"Path where the report command writes the self-contained HTML report, or empty to skip it"`
+
+	// Suggestion patch
+	SuggestionPatchFile string `json:"suggestion_patch_file" env:"PLUGIN_SUGGESTION_PATCH_FILE" default:"../output/suggestions.patch" help:"Path where the patch command writes the suggestion blocks of the review output as a git patch, or - for stdout"`
+	ApplySuggestions    bool   `json:"apply_suggestions" env:"PLUGIN_APPLY_SUGGESTIONS" default:"false" help:"Have the patch command apply the suggestions to the working tree, which must match source_sha, instead of writing a patch"`
+
+	// Suggestion verification
+	VerifySuggestions      string `json:"verify_suggestions" env:"PLUGIN_VERIFY_SUGGESTIONS" default:"annotate" help:"What publish does with suggestion blocks on Go files that fail go/parser or gofmt: annotate (add a note to the comment), drop (remove the suggestion) or off"`
+	VerifySuggestionsBuild bool   `json:"verify_suggestions_build" env:"PLUGIN_VERIFY_SUGGESTIONS_BUILD" default:"false" help:"Also run go build and go vet offline on the package of each Go suggestion, in a scratch copy of source_sha"`
+
+	// Language-aware guidance
+	EnableLanguageGuidance bool   `json:"enable_language_guidance" env:"PLUGIN_ENABLE_LANGUAGE_GUIDANCE" default:"true" help:"Add checklists for the languages of the changed files"`
+	LanguageRulesPath      string `json:"language_rules_path" env:"PLUGIN_LANGUAGE_RULES_PATH" default:".harness/rules/languages" help:"Directory of <language>.md files that replace the built-in checklists"`
+
+	// Pull request context
+	PRTitle           string `json:"pr_title" env:"PLUGIN_PR_TITLE" fallback:"DRONE_PULL_REQUEST_TITLE" help:"Pull request title"`
+	PRDescription     string `json:"pr_description" env:"PLUGIN_PR_DESCRIPTION" secret:"true" help:"Pull request description"`
+	PRDescriptionFile string `json:"pr_description_file" env:"PLUGIN_PR_DESCRIPTION_FILE" help:"File containing the pull request description"`
+	PREventFile       string `json:"pr_event_file" env:"PLUGIN_PR_EVENT_FILE" fallback:"GITHUB_EVENT_PATH" secret:"true" help:"Pull request event payload used to fill in a missing title or description"`
+	Ticket            string `json:"ticket" env:"PLUGIN_TICKET" secret:"true" help:"Text of the ticket linked to the pull request"`
+	TicketFile        string `json:"ticket_file" env:"PLUGIN_TICKET_FILE" help:"File containing the text of the linked ticket"`
+	IncludeCommitLog  bool   `json:"include_commit_log" env:"PLUGIN_INCLUDE_COMMIT_LOG" default:"true" help:"Include the commit messages between merge_base_sha and source_sha"`
+	ReviewDescription bool   `json:"review_description" env:"PLUGIN_REVIEW_DESCRIPTION" default:"false" help:"Ask the model to check that the description matches the implementation"`
+
+	// Commit hygiene review
+	EnableCommitReview     bool   `json:"enable_commit_review" env:"PLUGIN_ENABLE_COMMIT_REVIEW" default:"false" help:"Review commit messages and whether the pull request mixes unrelated changes; publish adds the detected violations to the review output"`
+	CommitConvention       string `json:"commit_convention" env:"PLUGIN_COMMIT_CONVENTION" default:"none" help:"Commit subject convention to enforce: conventional or none"`
+	CommitTypes            string `json:"commit_types" env:"PLUGIN_COMMIT_TYPES" default:"feat,fix,docs,style,refactor,perf,test,build,ci,chore,revert" help:"Comma-separated types allowed by the conventional commit convention"`
+	CommitMaxSubjectLength int    `json:"commit_max_subject_length" env:"PLUGIN_COMMIT_MAX_SUBJECT_LENGTH" default:"72" help:"Maximum commit subject length, or 0 for no limit"`
+	CommitRequireSignOff   bool   `json:"commit_require_sign_off" env:"PLUGIN_COMMIT_REQUIRE_SIGN_OFF" default:"false" help:"Require a Signed-off-by trailer on every commit"`
+
+	// ReviewProfile is the profile selected by Profile, resolved at runtime by
+	// WithProfile; it is not configurable
+	ReviewProfile *Profile `json:"-"`
+
+	// Context is gathered from the repository at runtime and is nil when
+	// the diff cannot be inspected; it is not configurable
+	Context *ReviewContext `json:"-"`
+}
+
+// SettingInfo describes how a single Settings field is configured
+type SettingInfo struct {
+	Name     string
+	Field    string
+	Env      string
+	Fallback string
+	Default  string
+	Help     string
+	Kind     reflect.Kind
+}
+
+// Flag returns the command-line flag name for the setting
+func (i SettingInfo) Flag() string {
+	return strings.ReplaceAll(i.Name, "_", "-")
+}
+
+// SettingsInfo returns the configuration metadata of every setting in declaration order
+func SettingsInfo() []SettingInfo {
+	t := reflect.TypeOf(Settings{})
+	var infos []SettingInfo
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		infos = append(infos, SettingInfo{
+			Name:     strings.Split(field.Tag.Get("json"), ",")[0],
+			Field:    field.Name,
+			Env:      env,
+			Fallback: field.Tag.Get("fallback"),
+			Default:  field.Tag.Get("default"),
+			Help:     field.Tag.Get("help"),
+			Kind:     field.Type.Kind(),
+		})
+	}
+	return infos
 }
 
 // NewSettings creates a new Settings instance from environment variables
 func NewSettings() Settings {
-	return Settings{
-		RepoName:     getEnv("PLUGIN_REPO_NAME", getEnv("DRONE_REPO_NAME", "")),
-		SourceBranch: getEnv("PLUGIN_SOURCE_BRANCH", getEnv("DRONE_SOURCE_BRANCH", "")),
-		TargetBranch: getEnv("PLUGIN_TARGET_BRANCH", getEnv("DRONE_TARGET_BRANCH", "")),
-		MergeBaseSha: getEnv("PLUGIN_MERGE_BASE_SHA", getEnv("DRONE_COMMIT_BEFORE", "")),
-		SourceSha:    getEnv("PLUGIN_SOURCE_SHA", getEnv("DRONE_COMMIT_SHA", "")),
-
-		EnableBugs:        getBoolEnv("PLUGIN_ENABLE_BUGS", true),
-		EnablePerformance: getBoolEnv("PLUGIN_ENABLE_PERFORMANCE", true),
-		EnableScalability: getBoolEnv("PLUGIN_ENABLE_SCALABILITY", true),
-		EnableCodeSmell:   getBoolEnv("PLUGIN_ENABLE_CODE_SMELL", true),
-
-		CommentCount:     getIntEnv("PLUGIN_COMMENT_COUNT", 10),
-		OutputFile:       getEnv("PLUGIN_OUTPUT_FILE", "../output/task.txt"),
-		ReviewOutputFile: getEnv("PLUGIN_REVIEW_OUTPUT_FILE", "../output/review.json"),
-		CustomRulesPath:  getEnv("PLUGIN_CUSTOM_RULES_PATH", ".harness/rules/review.md"),
+	var settings Settings
+	v := reflect.ValueOf(&settings).Elem()
+	for _, info := range SettingsInfo() {
+		field := v.FieldByName(info.Field)
+		switch info.Kind {
+		case reflect.Bool:
+			defaultValue, _ := strconv.ParseBool(info.Default)
+			if info.Fallback != "" {
+				defaultValue = getBoolEnv(info.Fallback, defaultValue)
+			}
+			field.SetBool(getBoolEnv(info.Env, defaultValue))
+		case reflect.Int:
+			defaultValue, _ := strconv.Atoi(info.Default)
+			if info.Fallback != "" {
+				defaultValue = getIntEnv(info.Fallback, defaultValue)
+			}
+			field.SetInt(int64(getIntEnv(info.Env, defaultValue)))
+		default:
+			defaultValue := info.Default
+			if info.Fallback != "" {
+				defaultValue = getEnv(info.Fallback, defaultValue)
+			}
+			field.SetString(getEnv(info.Env, defaultValue))
+		}
 	}
+	return settings
+}
+
+// Validate reports settings that would make a run fail or produce a misleading prompt
+func (s Settings) Validate() error {
+	var errs []error
+	if s.OutputFile == "" {
+		errs = append(errs, fmt.Errorf("output_file is required"))
+	}
+	if s.ReviewOutputFile == "" {
+		errs = append(errs, fmt.Errorf("review_output_file is required"))
+	}
+	if s.CommentCount < 1 {
+		errs = append(errs, fmt.Errorf("comment_count must be at least 1, got %d", s.CommentCount))
+	}
+	if _, err := s.OutputFileMode(); err != nil {
+		errs = append(errs, err)
+	}
+	if !strings.EqualFold(s.CommitConvention, "conventional") && !strings.EqualFold(s.CommitConvention, "none") && s.CommitConvention != "" {
+		errs = append(errs, fmt.Errorf("commit_convention must be conventional or none, got %q", s.CommitConvention))
+	}
+	if s.CommitMaxSubjectLength < 0 {
+		errs = append(errs, fmt.Errorf("commit_max_subject_length must not be negative, got %d", s.CommitMaxSubjectLength))
+	}
+	if s.FunctionContextMaxLines < 0 {
+		errs = append(errs, fmt.Errorf("function_context_max_lines must not be negative, got %d", s.FunctionContextMaxLines))
+	}
+	if s.MaxTokens < 0 {
+		errs = append(errs, fmt.Errorf("max_tokens must not be negative, got %d", s.MaxTokens))
+	}
+	if _, err := LookupTokenizer(s.Tokenizer); err != nil {
+		errs = append(errs, err)
+	}
+	if _, ok := promptTemplates[s.PromptTemplate]; !ok && s.PromptTemplate != "" {
+		errs = append(errs, fmt.Errorf("prompt_template must be one of %s, got %q", strings.Join(PromptTemplateNames(), ", "), s.PromptTemplate))
+	}
+	if _, err := loadPromptCatalog(s.PromptLanguage); err != nil {
+		errs = append(errs, fmt.Errorf("prompt_language: %w", err))
+	}
+	switch s.VerifySuggestions {
+	case "", verifyOff, verifyAnnotate, verifyDrop:
+	default:
+		errs = append(errs, fmt.Errorf("verify_suggestions must be annotate, drop or off, got %q", s.VerifySuggestions))
+	}
+	switch s.CoverageFormat {
+	case "", coverageAuto, coverageGo, coverageLCOV, coverageCobertura:
+	default:
+		errs = append(errs, fmt.Errorf("coverage_format must be auto, go, lcov or cobertura, got %q", s.CoverageFormat))
+	}
+	if _, err := ParseMigrationGlobs(s.MigrationGlobs); err != nil {
+		errs = append(errs, fmt.Errorf("migration_globs: %w", err))
+	}
+	if _, err := ParseTestPatterns(s.TestPatterns); err != nil {
+		errs = append(errs, fmt.Errorf("test_patterns: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// ManifestPath returns the manifest location, defaulting to manifest.json next to the output file.
+// It is empty when the prompt goes to stdout and no manifest file was requested.
+func (s Settings) ManifestPath() string {
+	if s.ManifestFile != "" {
+		return s.ManifestFile
+	}
+	if s.OutputFile == StdoutPath {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(s.OutputFile), "manifest.json")
+}
+
+// UsesStdout reports whether standard output carries the prompt rather than status messages
+func (s Settings) UsesStdout() bool {
+	return s.DryRun || s.OutputFile == StdoutPath
+}
+
+// OutputFileMode parses the octal permission bits used for generated files
+func (s Settings) OutputFileMode() (os.FileMode, error) {
+	if s.FileMode == "" {
+		return 0644, nil
+	}
+	mode, err := strconv.ParseUint(s.FileMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q: expected octal permissions such as 0644", s.FileMode)
+	}
+	return os.FileMode(mode), nil
 }
 
 // getEnv retrieves an environment variable with a default fallback
@@ -82,4 +323,3 @@ func getIntEnv(key string, defaultValue int) int {
 	}
 	return intValue
 }
-
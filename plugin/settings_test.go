@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -145,6 +146,27 @@ func TestNewSettings(t *testing.T) {
 	}
 }
 
+func TestNewSettingsOutputHandling(t *testing.T) {
+	os.Clearenv()
+	settings := NewSettings()
+	if settings.FileMode != "0644" {
+		t.Errorf("FileMode = %v, want 0644", settings.FileMode)
+	}
+	if settings.NoOverwrite {
+		t.Error("NoOverwrite should default to false")
+	}
+
+	os.Setenv("PLUGIN_FILE_MODE", "0600")
+	os.Setenv("PLUGIN_NO_OVERWRITE", "true")
+	settings = NewSettings()
+	if settings.FileMode != "0600" {
+		t.Errorf("FileMode = %v, want 0600", settings.FileMode)
+	}
+	if !settings.NoOverwrite {
+		t.Error("NoOverwrite should be true when PLUGIN_NO_OVERWRITE=true")
+	}
+}
+
 func TestGetBoolEnv(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -204,3 +226,118 @@ func TestGetIntEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestManifestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		expected string
+	}{
+		{"derived from output file", Settings{OutputFile: "../output/task.txt"}, "../output/manifest.json"},
+		{"explicit manifest file", Settings{OutputFile: "../output/task.txt", ManifestFile: "/tmp/run.json"}, "/tmp/run.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.ManifestPath(); got != tt.expected {
+				t.Errorf("ManifestPath() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOutputFileMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileMode  string
+		expected  os.FileMode
+		wantError bool
+	}{
+		{"empty uses default", "", 0644, false},
+		{"octal with leading zero", "0600", 0600, false},
+		{"octal without leading zero", "640", 0640, false},
+		{"symbolic is rejected", "rw-r--r--", 0, true},
+		{"out of range", "07777", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := Settings{FileMode: tt.fileMode}.OutputFileMode()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("OutputFileMode() error = %v, wantError %v", err, tt.wantError)
+			}
+			if mode != tt.expected {
+				t.Errorf("OutputFileMode() = %v, want %v", mode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUsesStdout(t *testing.T) {
+	tests := []struct {
+		name         string
+		settings     Settings
+		usesStdout   bool
+		manifestPath string
+	}{
+		{"file output", Settings{OutputFile: "../output/task.txt"}, false, "../output/manifest.json"},
+		{"stdout output", Settings{OutputFile: StdoutPath}, true, ""},
+		{"stdout with explicit manifest", Settings{OutputFile: StdoutPath, ManifestFile: "out/manifest.json"}, true, "out/manifest.json"},
+		{"dry run", Settings{OutputFile: "../output/task.txt", DryRun: true}, true, "../output/manifest.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.UsesStdout(); got != tt.usesStdout {
+				t.Errorf("UsesStdout() = %v, want %v", got, tt.usesStdout)
+			}
+			if got := tt.settings.ManifestPath(); got != tt.manifestPath {
+				t.Errorf("ManifestPath() = %v, want %v", got, tt.manifestPath)
+			}
+		})
+	}
+}
+
+func TestSettingsInfo(t *testing.T) {
+	infos := SettingsInfo()
+	if len(infos) == 0 {
+		t.Fatal("SettingsInfo() returned no settings")
+	}
+
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		if seen[info.Name] {
+			t.Errorf("Duplicate setting name %s", info.Name)
+		}
+		seen[info.Name] = true
+
+		if info.Env != "PLUGIN_"+strings.ToUpper(info.Name) {
+			t.Errorf("Setting %s uses env %s, want PLUGIN_%s", info.Name, info.Env, strings.ToUpper(info.Name))
+		}
+		if info.Help == "" {
+			t.Errorf("Setting %s has no help text", info.Name)
+		}
+	}
+
+	if !seen["repo_name"] || !seen["comment_count"] {
+		t.Error("SettingsInfo() should include repo_name and comment_count")
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	os.Clearenv()
+	if err := NewSettings().Validate(); err != nil {
+		t.Errorf("Default settings should be valid: %v", err)
+	}
+
+	invalid := Settings{CommentCount: 0, FileMode: "999", CommitConvention: "gitmoji", CommitMaxSubjectLength: -1, TestPatterns: "py=test_{name}.py", CoverageFormat: "jacoco", MigrationGlobs: "db/[x", FunctionContextMaxLines: -1, MaxTokens: -1, Tokenizer: "gpt", PromptTemplate: "v9", PromptLanguage: "fr", VerifySuggestions: "reject"}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("Validate() should reject invalid settings")
+	}
+	for _, want := range []string{"output_file", "review_output_file", "comment_count", "invalid file mode", "commit_convention", "commit_max_subject_length", "function_context_max_lines", "max_tokens", "tokenizer", "prompt_template", "prompt_language", "verify_suggestions", "test_patterns", "coverage_format", "migration_globs"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error should mention %s, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RenderSummary renders the summary and comment counts of a review output as a
+// Markdown pull request comment
+func RenderSummary(output ReviewOutput) string {
+	var b strings.Builder
+	b.WriteString("## Review Summary\n\n")
+
+	summary := output.Summary
+	if summary == nil {
+		summary = &ReviewSummary{}
+	}
+	if summary.Overview != "" {
+		b.WriteString(strings.TrimSpace(summary.Overview) + "\n\n")
+	} else {
+		b.WriteString("The review output has no summary.\n\n")
+	}
+
+	if len(summary.RiskAreas) > 0 {
+		b.WriteString("### Risk Areas\n\n")
+		for _, risk := range summary.RiskAreas {
+			b.WriteString("- " + strings.Join(strings.Fields(risk), " ") + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	comments := make(map[string]int)
+	for _, r := range output.Reviews {
+		comments[r.FilePath]++
+	}
+	if len(summary.Walkthrough) > 0 {
+		b.WriteString("### Walkthrough\n\n")
+		b.WriteString("| File | Changes | Comments |\n")
+		b.WriteString("|------|---------|----------|\n")
+		for _, f := range summary.Walkthrough {
+			fmt.Fprintf(&b, "| `%s` | %s | %d |\n", markdownCell(f.FilePath), markdownCell(f.Summary), comments[f.FilePath])
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("### Findings\n\n")
+	if len(output.Reviews) == 0 {
+		b.WriteString("No inline comments.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d inline comments: %s.\n", len(output.Reviews), typeCounts(output))
+	return b.String()
+}
+
+// typeCounts lists the number of comments of each type, most frequent first,
+// as in "2 bug, 1 performance"
+func typeCounts(output ReviewOutput) string {
+	counts := output.CountByType()
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		label := t
+		if label == "" {
+			label = "uncategorized"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[t], label))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownCell makes text safe to place in a Markdown table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`)
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "<br>"), "\n", "<br>")
+}
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderSummary(t *testing.T) {
+	output := ReviewOutput{
+		Reviews: []ReviewComment{
+			{FilePath: "auth/login.go", Type: "bug"},
+			{FilePath: "auth/login.go", Type: "bug"},
+			{FilePath: "api/handler.go", Type: "performance"},
+		},
+		Summary: &ReviewSummary{
+			Overview:    "Adds rate limiting to the login endpoint.",
+			RiskAreas:   []string{"The limiter key ignores\nproxies."},
+			Walkthrough: []FileSummary{{FilePath: "auth/login.go", Summary: "Checks the limiter | returns 429"}, {FilePath: "README.md", Summary: "Documents the limit"}},
+		},
+	}
+
+	want := "## Review Summary\n\n" +
+		"Adds rate limiting to the login endpoint.\n\n" +
+		"### Risk Areas\n\n" +
+		"- The limiter key ignores proxies.\n\n" +
+		"### Walkthrough\n\n" +
+		"| File | Changes | Comments |\n" +
+		"|------|---------|----------|\n" +
+		"| `auth/login.go` | Checks the limiter \\| returns 429 | 2 |\n" +
+		"| `README.md` | Documents the limit | 0 |\n\n" +
+		"### Findings\n\n" +
+		"3 inline comments: 2 bug, 1 performance.\n"
+	if got := RenderSummary(output); got != want {
+		t.Errorf("RenderSummary() =\n%s\nwant\n%s", got, want)
+	}
+
+	got := RenderSummary(ReviewOutput{})
+	for _, expected := range []string{"The review output has no summary.", "No inline comments."} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("RenderSummary() without a summary should contain %q, got:\n%s", expected, got)
+		}
+	}
+	if strings.Contains(got, "### Walkthrough") {
+		t.Error("RenderSummary() should omit the walkthrough without a summary")
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	os.Clearenv()
+	tempDir := t.TempDir()
+	reviewFile := filepath.Join(tempDir, "review.json")
+	summaryFile := filepath.Join(tempDir, "summary.md")
+	review := `{"reviews": [], "summary": {"overview": "Renames a flag.", "walkthrough": [{"file_path": "cli.go", "summary": "New flag name"}]}}`
+	if err := os.WriteFile(reviewFile, []byte(review), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := Run([]string{"summary", "-review-output-file", reviewFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "## Review Summary\n\nRenames a flag.\n") {
+		t.Errorf("summary should print the Markdown to stdout, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"summary", "-review-output-file", reviewFile, "-summary-file", summaryFile}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	content, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("Failed to read summary file: %v", err)
+	}
+	if !strings.Contains(string(content), "| `cli.go` | New flag name | 0 |") {
+		t.Errorf("Summary file should contain the walkthrough, got:\n%s", content)
+	}
+	if code := Run([]string{"summary", "-review-output-file", reviewFile, "-summary-file", summaryFile, "-no-overwrite"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Run() = %d, want 1 for an existing summary file with no_overwrite", code)
+	}
+
+	if err := os.WriteFile(reviewFile, []byte(`{"reviews": [], "summary": {"walkthrough": [{"summary": "x"}]}}`), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+	stderr.Reset()
+	if code := Run([]string{"summary", "-review-output-file", reviewFile}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "summary walkthrough 0: file_path is required") {
+		t.Errorf("Run() = %d, want 1 with the invalid walkthrough reported, got:\n%s", code, stderr.String())
+	}
+}
@@ -1,67 +1,97 @@
 package plugin
 
-const PromptTemplate = `assume the "{{.RepoName}}" working directory is a valid git repository.
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
 
-You are an expert software engineer specialized in code reviews.
-Your task is to analyze pull request diffs and add pr reviews. you can get the changes by running this command
-` + "```" + `
-git diff --color=never {{.MergeBaseSha}}...{{.SourceSha}} | awk '/^@@/{gsub(/.*-/,"",$0);gsub(/,.*\+/," ",$0);gsub(/,.*/,"",$0);split($0,n," ");ol=n[1];nl=n[2];print "=== OLD:"ol" NEW:"nl" ===";next}/^-/{print "OLD:"ol" "$0;ol++;next}/^+/{print "NEW:"nl" "$0;nl++;next}/^ /{print "CTX:"ol"/"nl" "$0;ol++;nl++;next}{print}'
-` + "```" + `
-if you need the context of the complete files or any other file after diff for your review you can access it in the working directory.
-if you don't find sha just give empty review and exit.
+// templateFS holds the prompt versions, the sections they share and the
+// message catalogs that translate them
+//
+//go:embed templates/*.tmpl templates/i18n/*.json
+var templateFS embed.FS
 
-Your review should include:
-- Provide comments only for lines that have been added, edited, or deleted
-- Only mention bugs or issues that are directly related to the syntax or functionality of the provided code changes.
-- You can also exact code change using suggestion markdown.
-- Do not mention that the file needs a thorough review or caution about potential issues.
-- Don't provide suggestions for minor code style issues, missing comments/documentation.
-- Comment should STRICTLY only have line numbers for changed lines. Ensure ` + "`line_number_start`" + ` and ` + "`line_number_end`" + ` are strictly and accurately computed based on the explained diff format with OLD and NEW line numbers. Comment line numbers MUST be within the range of changes shown in the diff, never outside it. You may use a python script to determine the line numbers presented at each line in the format of ` + "`NEW:77 CHANGES\\nOLD:70 CHANGES`" + `. IF the changes are in NEW lines, use that for the comment line numbers.
-- You are encouraged to use Markdown for your response to format your feedback effectively.
+// DefaultPromptTemplate is the prompt version rendered when prompt_template is empty
+const DefaultPromptTemplate = "v1"
 
-Follow strictly these guidelines:{{if .EnableBugs}}
-- Look for critical bugs like possible Null pointer exceptions, division by zero, or other logical errors.{{end}}{{if .EnablePerformance}}
-- Look for performance issues like avoid nested for loops.{{end}}{{if .EnableScalability}}
-- Look for scalability issues like overflow of memory due to reading of large strings.{{end}}{{if .EnableCodeSmell}}
-- Look for code smells{{end}}
-- Do not make more than {{.CommentCount}} comments per PR unless they are necessary.
-- Characterize each comment as a bug, code smell, performance issue, scalability concern, or create a new category if none of these apply.
-- Do not provide positive comments like good refactoring. Stricly review code for mentioned rules.
-- STRICTLY desist from making any comments that require upto date information since your cutoff. Do NOT comment on new versions of packages that you might not be aware off. Example Go 1.24.4 does exist after your knowledge cutoff.
-- STRICTLY Desist from making comments for missing imports unless you have seen the whole file and see that import is actually missing.
-- In a Git repository, if the file {{.CustomRulesPath}} exists, use the relevant and sensible instructions specified in that file as part of the pull request review process.
+// promptTemplates maps each prompt version to the files it is built from, in
+// order. Every version ends with the shared sections in sections.tmpl.
+var promptTemplates = map[string][]string{
+	"v1":         {"v1.tmpl"},
+	"v2-strict":  {"v2.tmpl", "v2-strict.tmpl"},
+	"v2-lenient": {"v2.tmpl", "v2-lenient.tmpl"},
+}
 
+// PromptTemplate is the text of the default prompt version
+var PromptTemplate = mustPromptText(DefaultPromptTemplate)
 
+// PromptTemplateNames lists the prompt versions in sorted order
+func PromptTemplateNames() []string {
+	names := make([]string, 0, len(promptTemplates))
+	for name := range promptTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-Code suggestion markdown are HIGHLY encouraged.
-Example of code suggestion markdown:
-` + "```suggestion" + `
-    {{"{{"}}changed_code{{"}}"}}
-` + "```" + `
-Make sure the {{"{{"}}changed_code{{"}}"}} is properly styled/linted and has right tabs and spaces as in original code. This is MUST.
+// PromptText returns the template text of a prompt version. An empty name
+// selects the default version.
+func PromptText(name string) (string, error) {
+	if name == "" {
+		name = DefaultPromptTemplate
+	}
+	files, ok := promptTemplates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt template %q (known: %s)", name, strings.Join(PromptTemplateNames(), ", "))
+	}
+	var b strings.Builder
+	for _, file := range append(files, "sections.tmpl") {
+		content, err := templateFS.ReadFile("templates/" + file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt template %s: %w", file, err)
+		}
+		b.Write(content)
+	}
+	return b.String(), nil
+}
 
-Important guidelines for line numbers:
-1. Pay careful attention to the line numbers in parentheses
-2. For added lines, only 'new line' numbers are available - these are the numbers you should reference
-3. For removed lines, only 'old line' numbers are available
-4. For context lines, both old and new line numbers are provided
-5. Your comments should ONLY reference line numbers that appear in the "new line" positions
-6. Focus your review ONLY on the added and removed and modified lines (those marked with "Added line")
+// mustPromptText returns the text of a prompt version known to be embedded
+func mustPromptText(name string) string {
+	text, err := PromptText(name)
+	if err != nil {
+		panic(err)
+	}
+	return text
+}
 
-NEVER comment on line numbers outside the explicitly shown changes in the diff.
+// parsePrompt parses the prompt version and language selected by settings
+func parsePrompt(settings Settings) (*template.Template, error) {
+	text, err := localizedPromptText(settings.PromptTemplate, settings.PromptLanguage)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
 
-JSON response format:
-{{"{{"}}
-"reviews": [
-    {{"{{"}}
-    "file_path": "path/to/file",
-    "line_number_start": 123,
-    "line_number_end": 125,
-    "type": "issue|performance|scalability|code_smell|new_category",
-    "review": "Your review for the file."
-    {{"}}"}}
-]
-{{"}}"}}
-
-Write the output to the file ` + "`{{.ReviewOutputFile}}`" + ` as well formated JSON. Create file if needed. File should be created even in case there are no comments.
-`
+// selectedPromptTemplate returns the name and content hash of the prompt
+// version selected by settings in its language, or an empty hash for an
+// unknown version or language
+func selectedPromptTemplate(settings Settings) (string, string) {
+	name := settings.PromptTemplate
+	if name == "" {
+		name = DefaultPromptTemplate
+	}
+	text, err := localizedPromptText(name, settings.PromptLanguage)
+	if err != nil {
+		return name, ""
+	}
+	return name, TemplateHash(text)
+}
@@ -212,3 +212,581 @@ func TestPromptTemplateConditionals(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptTemplateLanguageGuidance(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Languages: []LanguageGuidance{
+				{ID: "go", Name: "Go", Files: []string{"main.go"}, Checks: []string{"Goroutine leaks", "defer inside loops"}},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	output := result.String()
+
+	for _, expected := range []string{
+		"- In the Go files of this change, specifically check for:\n  - Goroutine leaks\n  - defer inside loops",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+	if strings.Contains(output, "Python") {
+		t.Error("Output should only contain guidance for languages present in the diff")
+	}
+}
+
+func TestPromptTemplatePullRequestContext(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:          "test-repo",
+		MergeBaseSha:      "abc",
+		SourceSha:         "def",
+		CommentCount:      10,
+		ReviewDescription: true,
+		Context: &ReviewContext{
+			PullRequest: &PullRequestInfo{
+				Title:       "Add retries",
+				Description: "Retries failed uploads three times.",
+				Commits:     []Commit{{SHA: "0123456789abcdef", Subject: "add retry loop", Body: "Uses backoff."}},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	output := result.String()
+
+	for _, expected := range []string{
+		"Title: Add retries",
+		"Retries failed uploads three times.",
+		"- 01234567 add retry loop\n    Uses backoff.",
+		"description_mismatch",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+
+	settings.ReviewDescription = false
+	settings.Context = nil
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "Pull request context") || strings.Contains(result.String(), "description_mismatch") {
+		t.Error("Output should not contain pull request context when none was gathered")
+	}
+}
+
+func TestPromptTemplateCommitReview(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:           "test-repo",
+		MergeBaseSha:       "abc",
+		SourceSha:          "def",
+		CommentCount:       10,
+		EnableCommitReview: true,
+		Context: &ReviewContext{
+			Commits: &CommitReview{
+				Rules:    []string{"Subjects are at most 72 characters long"},
+				Findings: []CommitFinding{{SHA: "0123456789abcdef", Subject: "wip", Rule: "vague-subject", Message: `Commit subject "wip" does not describe the change.`}},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	output := result.String()
+
+	for _, expected := range []string{
+		"Review the commit series listed above",
+		"  - Subjects are at most 72 characters long",
+		`  - commit:01234567: Commit subject "wip" does not describe the change.`,
+		"|commit_hygiene|",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+
+	settings.EnableCommitReview = false
+	settings.Context = nil
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "commit_hygiene") {
+		t.Error("Output should not mention commit hygiene when commit review is disabled")
+	}
+}
+
+func TestPromptTemplateDiffOverview(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	files := []ChangedFile{
+		{Path: "auth/login.go", Status: "M", Additions: 12, Deletions: 3},
+		{Path: "auth/login_test.go", Status: "M", Additions: 20},
+	}
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context:      &ReviewContext{Files: files, Overview: AnalyzeRisk(files, nil)},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	output := result.String()
+
+	for _, expected := range []string{
+		"Change overview: 2 files changed, +32 -3 lines, tests changed. Review the riskiest files first.",
+		"| File | Type | Status | Added | Removed | Risk | Reasons |",
+		"| auth/login.go | Go | M | 12 | 3 | medium (3) | touches authentication or security code |",
+		"| auth/login_test.go | Go | M | 20 | 0 | medium (3) | touches authentication or security code |",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output should contain: %s\ngot:\n%s", expected, output)
+		}
+	}
+
+	settings.Context = nil
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "Change overview") {
+		t.Error("Output should not contain the change overview when no diff was inspected")
+	}
+}
+
+func TestPromptTemplateMissingTests(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:           "test-repo",
+		MergeBaseSha:       "abc",
+		SourceSha:          "def",
+		CommentCount:       10,
+		EnableTestCoverage: true,
+		Context: &ReviewContext{
+			MissingTests: []MissingTest{{Path: "app/cart.ts", Expected: []string{"app/cart.test.ts", "app/cart.spec.ts"}}},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	output := result.String()
+
+	for _, expected := range []string{
+		"Source files changed without changes to their conventional test files:\n- app/cart.ts (expected app/cart.test.ts or app/cart.spec.ts)",
+		`using the type "test_coverage"`,
+		"|test_coverage|",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+
+	settings.EnableTestCoverage = false
+	settings.Context = nil
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "test_coverage") || strings.Contains(result.String(), "conventional test files") {
+		t.Error("Output should not mention missing tests when none were found and the category is disabled")
+	}
+}
+
+func TestPromptTemplateCoverage(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Coverage: &CoverageReport{
+				File:       "coverage.out",
+				Executable: 9,
+				Uncovered:  4,
+				Files:      []UncoveredFile{{Path: "plugin/cli.go", Lines: []int{12, 13, 14, 40}, Executable: 9}},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	expected := "Test coverage from coverage.out: 4 of 9 changed executable lines were not run by any test."
+	if !strings.Contains(result.String(), expected) || !strings.Contains(result.String(), "- plugin/cli.go: 12-14, 40\n") {
+		t.Errorf("Output should list the uncovered changed lines, got:\n%s", result.String())
+	}
+
+	settings.Context.Coverage = &CoverageReport{File: "coverage.out"}
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "Test coverage from") {
+		t.Error("Output should not mention coverage when no changed line is in the report")
+	}
+}
+
+func TestPromptTemplateToolFindings(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			ToolFindings: &ToolFindings{
+				Findings: []ToolFinding{{Tool: "golangci-lint", Rule: "errcheck", Path: "plugin/cli.go", StartLine: 42, EndLine: 42, Message: "Error return value is not checked"}},
+				Omitted:  3,
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"Do not repeat these findings; focus on issues linters cannot find",
+		"- plugin/cli.go:42 [golangci-lint errcheck] Error return value is not checked\n",
+		"- 3 more findings omitted\n",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+}
+
+func TestPromptTemplateDependencies(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Dependencies: &DependencyReview{
+				Changes: []DependencyChange{{Manifest: "go.mod", Name: "github.com/a/b", Change: depUpgraded, OldVersion: "v1.2.0", NewVersion: "v2.0.0", Major: true}},
+				Notes:   []string{"go.mod changed dependencies but none of its lockfiles (go.sum) changed"},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"- go.mod: upgraded github.com/a/b v1.2.0 -> v2.0.0 (major version bump)\n",
+		"- Note: go.mod changed dependencies but none of its lockfiles (go.sum) changed\n",
+		"Do not claim that a version does or does not exist.",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+}
+
+func TestPromptTemplateMigrations(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Migrations: &MigrationReview{
+				Files:  []MigrationFile{{Path: "db/migrations/0003_users.up.sql", Framework: "golang-migrate", Hints: []string{"adds a NOT NULL column without a default"}}},
+				Checks: migrationChecks,
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"- This change includes database migrations. For each of them, specifically check for:\n  - Locking:",
+		"  - db/migrations/0003_users.up.sql (golang-migrate): adds a NOT NULL column without a default\n",
+		`Report migration problems using the type "migration".`,
+		"|migration|",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+
+	settings.Context = nil
+	result.Reset()
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	if strings.Contains(result.String(), "migration") {
+		t.Error("Output should not mention migrations when the change has none")
+	}
+	if !strings.Contains(result.String(), "Follow strictly these guidelines:\n- Do not make more than 10 comments") {
+		t.Error("The guideline list should have no blank line when the change has no migrations")
+	}
+}
+
+func TestPromptTemplateAPICompat(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			APIChanges: &APIReview{
+				Changes: []APIChange{
+					{Package: "client", Kind: "func", Name: "New", Change: "changed", Old: "func New(string) *Client", New: "func New(string, int) *Client"},
+					{Package: "client", Kind: "method", Name: "Client.Close", Change: "removed", Old: "func (*Client) Close() error"},
+				},
+				Omitted: 3,
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"Potential breaking changes to the exported Go API, found by comparing the packages at both commits:\n",
+		"- client: changed func New: func New(string) *Client -> func New(string, int) *Client\n",
+		"- client: removed method Client.Close: func (*Client) Close() error\n",
+		"- 3 more changes omitted\n",
+		`using the type "api_compat".`,
+		"|api_compat|",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+}
+
+func TestPromptTemplateFunctionContext(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Functions: []FunctionContext{{
+				Path: "calc.go",
+				Functions: []EnclosingFunction{{
+					Header:    "func Add(a, b int) int",
+					StartLine: 4,
+					EndLine:   6,
+					Changes:   []int{5},
+					Lines:     []string{"func Add(a, b int) int {", "\treturn a + b + 0", "}"},
+				}},
+				Omitted: 2,
+			}},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"Enclosing functions of the changed hunks, read from def with their NEW line numbers.",
+		"\ncalc.go:4-6 func Add(a, b int) int (changes at line 5)\n```\n4 | func Add(a, b int) int {\n5 | \treturn a + b + 0\n6 | }\n```\n",
+		"2 more enclosing functions in calc.go are omitted by the size limit.\n\nYour review should include:",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+}
+
+func TestPromptTemplateCallers(t *testing.T) {
+	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	settings := Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Context: &ReviewContext{
+			Callers: &CallerReview{
+				Functions: []ChangedFunction{{
+					Name:    "Total",
+					Path:    "cart/cart.go",
+					Line:    5,
+					Callers: []CallSite{{Path: "api/handler.go", Line: 12, Caller: "Checkout", Snippet: "return cart.Total(prices)"}},
+					Omitted: 4,
+				}},
+			},
+		},
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, settings); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	for _, expected := range []string{
+		"Call sites of the changed Go functions elsewhere in the module, read from def.",
+		"- Total (cart/cart.go:5), 4 more call sites omitted:\n  - api/handler.go:12 in Checkout: return cart.Total(prices)\n\nYour review should include:",
+	} {
+		if !strings.Contains(result.String(), expected) {
+			t.Errorf("Output should contain: %s", expected)
+		}
+	}
+}
+
+func TestPromptTemplateVersions(t *testing.T) {
+	if got, want := strings.Join(PromptTemplateNames(), ","), "v1,v2-lenient,v2-strict"; got != want {
+		t.Errorf("PromptTemplateNames() = %s, want %s", got, want)
+	}
+	if _, err := PromptText("v9"); err == nil || !strings.Contains(err.Error(), "v1, v2-lenient, v2-strict") {
+		t.Errorf("PromptText(v9) error = %v, want the known versions listed", err)
+	}
+	if text, err := PromptText(""); err != nil || text != PromptTemplate {
+		t.Errorf("PromptText(\"\") should return the default version, got error %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected []string
+		absent   []string
+	}{
+		{"v1", []string{"JSON response format", "Follow strictly these guidelines"}, []string{"Review strictly", "Review leniently"}},
+		{"v2-strict", []string{"Review strictly.", "Look for:\n- Bugs:", "Do not make more than 10 comments.", `"type": "issue|performance|scalability|code_smell|api_compat|new_category"`}, []string{"Review leniently", "{{."}},
+		{"v2-lenient", []string{"Review leniently.", "Change overview: 1 files changed", "Write the review as JSON to the file `review.json`"}, []string{"Review strictly", "{{."}},
+	}
+	hashes := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := Settings{
+				RepoName:         "test-repo",
+				MergeBaseSha:     "abc",
+				SourceSha:        "def",
+				EnableBugs:       true,
+				CommentCount:     10,
+				ReviewOutputFile: "review.json",
+				PromptTemplate:   tt.name,
+				Context: &ReviewContext{
+					Overview:   &DiffOverview{Stats: DiffStats{Files: 1}},
+					APIChanges: &APIReview{Changes: []APIChange{{Package: "p", Kind: apiFunc, Name: "F", Change: apiRemoved}}},
+				},
+			}
+			prompt, err := RenderPrompt(settings)
+			if err != nil {
+				t.Fatalf("RenderPrompt() failed: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(string(prompt), expected) {
+					t.Errorf("Prompt should contain: %s", expected)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(string(prompt), absent) {
+					t.Errorf("Prompt should not contain: %s", absent)
+				}
+			}
+
+			_, hash := selectedPromptTemplate(settings)
+			if hashes[hash] {
+				t.Errorf("hash %s is shared with another version", hash)
+			}
+			hashes[hash] = true
+		})
+	}
+}
+
+func TestPromptTemplateSummary(t *testing.T) {
+	for _, version := range PromptTemplateNames() {
+		for _, enabled := range []bool{true, false} {
+			settings := Settings{RepoName: "test-repo", CommentCount: 10, PromptTemplate: version, EnableSummary: enabled}
+			prompt, err := RenderPrompt(settings)
+			if err != nil {
+				t.Fatalf("RenderPrompt(%s) failed: %v", version, err)
+			}
+			// The example uses the braces of the rest of the version's JSON example
+			brace := "{"
+			if version == "v1" {
+				brace = "{{"
+			}
+			for _, expected := range []string{`fill in the "summary" object`, "],\n\"summary\": " + brace + "\n", `"walkthrough": [`, "\n        " + brace + "\n"} {
+				if strings.Contains(string(prompt), expected) != enabled {
+					t.Errorf("%s with enable_summary=%v: prompt containing %q = %v", version, enabled, expected, !enabled)
+				}
+			}
+		}
+	}
+}
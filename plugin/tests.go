@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// TestPattern maps source files with extension Ext to the path of their test file.
+// Template may use {dir}, {testdir}, {name} and {ext} and may contain * wildcards.
+type TestPattern struct {
+	Ext      string
+	Template string
+}
+
+// MissingTest is a changed source file whose conventional test files were not changed
+type MissingTest struct {
+	Path     string   `json:"path"`
+	Expected []string `json:"expected"`
+}
+
+// ExpectedList joins the expected test paths for the prompt
+func (m MissingTest) ExpectedList() string {
+	return strings.Join(m.Expected, " or ")
+}
+
+// defaultTestPatterns are the test file conventions of the supported languages
+var defaultTestPatterns = append([]TestPattern{
+	{".go", "{dir}/{name}_test.go"},
+	{".py", "{dir}/test_{name}.py"},
+	{".py", "{dir}/{name}_test.py"},
+	{".py", "{dir}/tests/test_{name}.py"},
+	{".py", "tests/test_{name}.py"},
+	{".py", "tests/*/test_{name}.py"},
+	{".java", "{testdir}/{name}Test.java"},
+	{".kt", "{testdir}/{name}Test.kt"},
+	{".rb", "spec/*/{name}_spec.rb"},
+	{".rb", "test/*/{name}_test.rb"},
+}, scriptTestPatterns()...)
+
+// scriptTestPatterns returns the Jest and Vitest conventions shared by the JavaScript family
+func scriptTestPatterns() []TestPattern {
+	var patterns []TestPattern
+	for _, ext := range []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"} {
+		patterns = append(patterns,
+			TestPattern{ext, "{dir}/{name}.test{ext}"},
+			TestPattern{ext, "{dir}/{name}.spec{ext}"},
+			TestPattern{ext, "{dir}/__tests__/{name}{ext}"},
+			TestPattern{ext, "{dir}/__tests__/{name}.test{ext}"},
+		)
+	}
+	return patterns
+}
+
+// ParseTestPatterns parses comma-separated ext=template entries such as
+// ".py=tests/unit/test_{name}.py" and appends them to the built-in patterns
+func ParseTestPatterns(spec string) ([]TestPattern, error) {
+	patterns := append([]TestPattern(nil), defaultTestPatterns...)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ext, template, ok := strings.Cut(entry, "=")
+		ext, template = strings.TrimSpace(ext), strings.TrimSpace(template)
+		if !ok || !strings.HasPrefix(ext, ".") || template == "" {
+			return nil, fmt.Errorf("invalid test pattern %q, want .ext=template", entry)
+		}
+		if _, err := path.Match(expandTestPattern(template, "dir", "name", ext), ""); err != nil {
+			return nil, fmt.Errorf("invalid test pattern %q: %w", entry, err)
+		}
+		patterns = append(patterns, TestPattern{Ext: ext, Template: template})
+	}
+	return patterns, nil
+}
+
+// expandTestPattern fills in the placeholders of a test path template
+func expandTestPattern(template, dir, name, ext string) string {
+	testdir := dir
+	if i := strings.Index(dir+"/", "src/main/"); i >= 0 {
+		testdir = dir[:i] + "src/test/" + strings.TrimPrefix(dir[i:], "src/main/")
+		testdir = strings.TrimSuffix(testdir, "/")
+	}
+	expanded := strings.NewReplacer("{dir}", dir, "{testdir}", testdir, "{name}", name, "{ext}", ext).Replace(template)
+	return strings.TrimPrefix(path.Clean(expanded), "./")
+}
+
+// FindMissingTests returns the added or modified source files for which the
+// patterns name a test file but none of those test files changed
+func FindMissingTests(files []ChangedFile, patterns []TestPattern) []MissingTest {
+	var tests []string
+	isTest := make(map[string]bool)
+	for _, f := range files {
+		if isTestFile(f.Path) || matchesTestPattern(f.Path, patterns) {
+			tests = append(tests, f.Path)
+			isTest[f.Path] = true
+		}
+	}
+
+	var missing []MissingTest
+	for _, f := range files {
+		if f.Status == "D" || f.Binary || isTest[f.Path] || !isSourceFile(f.Path) {
+			continue
+		}
+		expected := expectedTests(f.Path, patterns)
+		if len(expected) == 0 || anyMatch(expected, tests) {
+			continue
+		}
+		missing = append(missing, MissingTest{Path: f.Path, Expected: expected})
+	}
+	return missing
+}
+
+// expectedTests expands every pattern for the extension of source
+func expectedTests(source string, patterns []TestPattern) []string {
+	ext := path.Ext(source)
+	dir := path.Dir(source)
+	name := strings.TrimSuffix(path.Base(source), ext)
+
+	var expected []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		if p.Ext != ext {
+			continue
+		}
+		candidate := expandTestPattern(p.Template, dir, name, ext)
+		if candidate != source && !seen[candidate] {
+			seen[candidate] = true
+			expected = append(expected, candidate)
+		}
+	}
+	return expected
+}
+
+// matchesTestPattern reports whether the file name of p follows the test naming of a pattern
+func matchesTestPattern(p string, patterns []TestPattern) bool {
+	for _, pattern := range patterns {
+		base := expandTestPattern(path.Base(pattern.Template), "", "*", pattern.Ext)
+		// A bare {name}{ext} under a test directory says nothing about the file name
+		if base == "*"+pattern.Ext {
+			continue
+		}
+		if ok, _ := path.Match(base, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// anyMatch reports whether any path matches any of the glob patterns
+func anyMatch(patterns, paths []string) bool {
+	for _, pattern := range patterns {
+		for _, p := range paths {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindMissingTests(t *testing.T) {
+	files := []ChangedFile{
+		{Path: "plugin/risk.go", Status: "M"},
+		{Path: "plugin/risk_test.go", Status: "M"},
+		{Path: "plugin/cli.go", Status: "M"},
+		{Path: "main.go", Status: "A"},
+		{Path: "web/src/app.tsx", Status: "M"},
+		{Path: "web/src/__tests__/app.test.tsx", Status: "M"},
+		{Path: "svc/handlers.py", Status: "M"},
+		{Path: "svc/src/main/java/com/acme/Billing.java", Status: "M"},
+		{Path: "svc/src/test/java/com/acme/BillingTest.java", Status: "A"},
+		{Path: "old.go", Status: "D"},
+		{Path: "db/schema.sql", Status: "M"},
+		{Path: "README.md", Status: "M"},
+	}
+
+	got := FindMissingTests(files, defaultTestPatterns)
+	want := []MissingTest{
+		{Path: "plugin/cli.go", Expected: []string{"plugin/cli_test.go"}},
+		{Path: "main.go", Expected: []string{"main_test.go"}},
+		{Path: "svc/handlers.py", Expected: []string{
+			"svc/test_handlers.py",
+			"svc/handlers_test.py",
+			"svc/tests/test_handlers.py",
+			"tests/test_handlers.py",
+			"tests/*/test_handlers.py",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindMissingTests() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFindMissingTestsCustomPatterns(t *testing.T) {
+	patterns, err := ParseTestPatterns(".kt={dir}/{name}Spec.kt, .py=tests/unit/test_{name}.py")
+	if err != nil {
+		t.Fatalf("ParseTestPatterns() failed: %v", err)
+	}
+	if len(patterns) != len(defaultTestPatterns)+2 {
+		t.Errorf("ParseTestPatterns() returned %d patterns, want the built-ins plus 2", len(patterns))
+	}
+
+	files := []ChangedFile{
+		{Path: "app/Cart.kt", Status: "M"},
+		{Path: "app/CartSpec.kt", Status: "M"},
+		{Path: "svc/orders.py", Status: "M"},
+		{Path: "tests/unit/test_orders.py", Status: "M"},
+	}
+	if got := FindMissingTests(files, patterns); len(got) != 0 {
+		t.Errorf("FindMissingTests() = %+v, want none when the custom test files changed", got)
+	}
+}
+
+func TestParseTestPatternsErrors(t *testing.T) {
+	for _, spec := range []string{"py=test_{name}.py", ".py", ".py=", ".py=[{name}.py"} {
+		if _, err := ParseTestPatterns(spec); err == nil {
+			t.Errorf("ParseTestPatterns(%q) should fail", spec)
+		}
+	}
+	if patterns, err := ParseTestPatterns(""); err != nil || len(patterns) != len(defaultTestPatterns) {
+		t.Errorf("ParseTestPatterns(\"\") = %d patterns, %v; want the built-ins", len(patterns), err)
+	}
+}
@@ -0,0 +1,494 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer counts the tokens a model would see for a text. Implementations
+// only need to be close enough to budget the prompt.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// defaultTokenizer names the tokenizer used when the setting is empty
+const defaultTokenizer = "bpe"
+
+// tokenizers are the registered tokenizers by name
+var tokenizers = map[string]Tokenizer{
+	"bpe":   bpeTokenizer{},
+	"chars": charTokenizer{},
+}
+
+// RegisterTokenizer makes a tokenizer selectable through the tokenizer setting,
+// for example an exact tokenizer for a specific model
+func RegisterTokenizer(name string, t Tokenizer) {
+	tokenizers[name] = t
+}
+
+// LookupTokenizer returns the tokenizer registered under name
+func LookupTokenizer(name string) (Tokenizer, error) {
+	if name == "" {
+		name = defaultTokenizer
+	}
+	t, ok := tokenizers[name]
+	if !ok {
+		names := make([]string, 0, len(tokenizers))
+		for n := range tokenizers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("tokenizer must be one of %s, got %q", strings.Join(names, ", "), name)
+	}
+	return t, nil
+}
+
+// charTokenizer assumes four characters per token
+type charTokenizer struct{}
+
+// CountTokens implements Tokenizer
+func (charTokenizer) CountTokens(text string) int {
+	return (len([]rune(text)) + 3) / 4
+}
+
+// bpeTokenizer approximates the byte pair encodings of current models without
+// shipping their vocabularies: it splits text the way their pre-tokenizers do
+// and estimates the tokens of each piece
+type bpeTokenizer struct{}
+
+// CountTokens implements Tokenizer
+func (bpeTokenizer) CountTokens(text string) int {
+	runes := []rune(text)
+	count := 0
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		j := i + 1
+		switch {
+		case r == '\n' || r == '\r':
+			for j < len(runes) && (runes[j] == '\n' || runes[j] == '\r') {
+				j++
+			}
+			count++
+		case unicode.IsSpace(r):
+			for j < len(runes) && unicode.IsSpace(runes[j]) && runes[j] != '\n' && runes[j] != '\r' {
+				j++
+			}
+			// A single space is merged into the piece that follows it
+			if j-i > 1 || j == len(runes) || runes[j] == '\n' || runes[j] == '\r' {
+				count += (j - i + 7) / 8
+			}
+		case unicode.IsLetter(r):
+			for j < len(runes) && unicode.IsLetter(runes[j]) && !isWordBoundary(runes[j-1], runes[j]) {
+				j++
+			}
+			count += wordTokens(runes[i:j])
+		case unicode.IsDigit(r):
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			// Numbers are split into groups of up to three digits
+			count += (j - i + 2) / 3
+		default:
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !unicode.IsLetter(runes[j]) && !unicode.IsDigit(runes[j]) {
+				j++
+			}
+			// Common operator pairs such as ":=", "//" and ");" merge
+			count += (j - i + 1) / 2
+		}
+		i = j
+	}
+	return count
+}
+
+// isWordBoundary reports whether a camelCase identifier starts a new word at next
+func isWordBoundary(prev, next rune) bool {
+	return unicode.IsLower(prev) && unicode.IsUpper(next)
+}
+
+// wordTokens estimates the tokens of a word: short ASCII words are usually a
+// single token, longer ones split every few characters, and other scripts
+// take about one token per character
+func wordTokens(word []rune) int {
+	ascii, other := 0, 0
+	for _, r := range word {
+		if r <= unicode.MaxASCII {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	tokens := other
+	switch {
+	case ascii == 0:
+	case ascii <= 10:
+		tokens++
+	default:
+		tokens += (ascii + 6) / 7
+	}
+	return tokens
+}
+
+// promptSections are the template blocks measured separately, in prompt order
+var promptSections = []string{
+	"profile_style",
+	"token_budget",
+	"diff_overview",
+	"missing_tests",
+	"coverage",
+	"tool_findings",
+	"dependencies",
+	"api_compat",
+	"pull_request",
+	"function_context",
+	"callers",
+	"language_guidance",
+	"migrations",
+	"commit_review",
+	"profile_rules",
+	"summary",
+	"summary_field",
+	"review_language",
+}
+
+// SectionTokens is the size of one section of the prompt
+type SectionTokens struct {
+	Name   string `json:"name"`
+	Tokens int    `json:"tokens"`
+}
+
+// TokenReport describes the estimated size of what the model reads: the prompt
+// itself and the diff it is asked to fetch
+type TokenReport struct {
+	Tokenizer string          `json:"tokenizer"`
+	Prompt    int             `json:"prompt"`
+	Diff      int             `json:"diff"`
+	Total     int             `json:"total"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+	Sections  []SectionTokens `json:"sections"`
+	Trimmed   *TrimReport     `json:"trimmed,omitempty"`
+}
+
+// TrimReport records what was left out to fit the token budget
+type TrimReport struct {
+	OmittedFiles    []string `json:"omitted_files,omitempty"`
+	OmittedSections []string `json:"omitted_sections,omitempty"`
+}
+
+// FileList joins the omitted files for the prompt
+func (t TrimReport) FileList() string {
+	return strings.Join(t.OmittedFiles, ", ")
+}
+
+// SectionList joins the omitted sections for the prompt
+func (t TrimReport) SectionList() string {
+	return strings.Join(t.OmittedSections, ", ")
+}
+
+// Pathspec returns the git diff arguments excluding the omitted files
+func (t TrimReport) Pathspec() string {
+	if len(t.OmittedFiles) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(" -- .")
+	for _, f := range t.OmittedFiles {
+		b.WriteString(" ':(exclude)" + strings.ReplaceAll(f, "'", `'\''`) + "'")
+	}
+	return b.String()
+}
+
+// MeasureTokens estimates the tokens of the rendered prompt, of each of its
+// sections and of the annotated diff in the context
+func MeasureTokens(settings Settings, name string, tokenizer Tokenizer) (*TokenReport, error) {
+	tmpl, err := parsePrompt(settings)
+	if err != nil {
+		return nil, err
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, settings); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	report := &TokenReport{
+		Tokenizer: name,
+		Prompt:    tokenizer.CountTokens(prompt.String()),
+		MaxTokens: settings.MaxTokens,
+	}
+	sections := 0
+	for _, section := range promptSections {
+		var b bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&b, section, settings); err != nil {
+			return nil, fmt.Errorf("failed to execute template %s: %w", section, err)
+		}
+		if tokens := tokenizer.CountTokens(b.String()); tokens > 0 {
+			report.Sections = append(report.Sections, SectionTokens{Name: section, Tokens: tokens})
+			sections += tokens
+		}
+	}
+	report.Sections = append([]SectionTokens{{Name: "instructions", Tokens: max(report.Prompt-sections, 0)}}, report.Sections...)
+
+	if ctx := settings.Context; ctx != nil {
+		omitted := make(map[string]bool)
+		if ctx.Trim != nil {
+			for _, f := range ctx.Trim.OmittedFiles {
+				omitted[f] = true
+			}
+			report.Trimmed = ctx.Trim
+		}
+		for _, d := range ctx.Diffs {
+			if !omitted[d.Path] {
+				report.Diff += diffTokens(d, tokenizer)
+			}
+		}
+	}
+	report.Total = report.Prompt + report.Diff
+	return report, nil
+}
+
+// diffTokens estimates the tokens of a file diff in the annotated format the prompt asks for
+func diffTokens(d FileDiff, tokenizer Tokenizer) int {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", d.Path, d.Path)
+	for _, h := range d.Hunks {
+		fmt.Fprintf(&b, "=== OLD:%d NEW:%d ===\n", h.OldStart, h.NewStart)
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case '+':
+				fmt.Fprintf(&b, "NEW:%d +%s\n", l.NewLine, l.Text)
+			case '-':
+				fmt.Fprintf(&b, "OLD:%d -%s\n", l.OldLine, l.Text)
+			default:
+				fmt.Fprintf(&b, "CTX:%d/%d  %s\n", l.OldLine, l.NewLine, l.Text)
+			}
+		}
+	}
+	return tokenizer.CountTokens(b.String())
+}
+
+// contextTrimmers drop optional context sections, least useful first, once
+// omitting files is not enough to fit the budget. Each reports whether the
+// section had content.
+var contextTrimmers = []struct {
+	section string
+	trim    func(ctx *ReviewContext) bool
+}{
+	{"callers", func(ctx *ReviewContext) bool {
+		had := ctx.Callers != nil
+		ctx.Callers = nil
+		return had
+	}},
+	{"function_context", func(ctx *ReviewContext) bool {
+		had := len(ctx.Functions) > 0
+		ctx.Functions = nil
+		return had
+	}},
+	{"tool_findings", func(ctx *ReviewContext) bool {
+		had := ctx.ToolFindings != nil
+		ctx.ToolFindings = nil
+		return had
+	}},
+	{"coverage", func(ctx *ReviewContext) bool {
+		had := ctx.Coverage != nil
+		ctx.Coverage = nil
+		return had
+	}},
+	{"missing_tests", func(ctx *ReviewContext) bool {
+		had := len(ctx.MissingTests) > 0
+		ctx.MissingTests = nil
+		return had
+	}},
+	{"dependencies", func(ctx *ReviewContext) bool {
+		had := ctx.Dependencies != nil
+		ctx.Dependencies = nil
+		return had
+	}},
+	{"api_compat", func(ctx *ReviewContext) bool {
+		had := ctx.APIChanges != nil
+		ctx.APIChanges = nil
+		return had
+	}},
+	// The commit review refers to the commit series of the pull request
+	// section, so it goes first
+	{"commit_review", func(ctx *ReviewContext) bool {
+		had := ctx.Commits != nil
+		ctx.Commits = nil
+		return had
+	}},
+	{"pull_request", func(ctx *ReviewContext) bool {
+		had := ctx.PullRequest != nil
+		ctx.PullRequest = nil
+		return had
+	}},
+	{"diff_overview", func(ctx *ReviewContext) bool {
+		had := ctx.Overview != nil
+		ctx.Overview = nil
+		return had
+	}},
+}
+
+// FitTokenBudget measures the prompt and, when it and the diff exceed
+// settings.MaxTokens, trims settings.Context in place: it omits the diffs of the
+// lowest-risk files first, always keeping the riskiest file, then drops optional
+// context sections. The prompt notes what was omitted. The returned report
+// describes the final prompt, which may still exceed the budget.
+func FitTokenBudget(settings Settings, tokenizer Tokenizer) (*TokenReport, error) {
+	name := settings.Tokenizer
+	if name == "" {
+		name = defaultTokenizer
+	}
+	report, err := MeasureTokens(settings, name, tokenizer)
+	if err != nil || settings.MaxTokens <= 0 || report.Total <= settings.MaxTokens || settings.Context == nil {
+		return report, err
+	}
+
+	ctx := settings.Context
+	ctx.Trim = &TrimReport{}
+	files := AnalyzeRisk(ctx.Files, ctx.Diffs).Files
+	for i := len(files) - 1; i > 0 && report.Total > settings.MaxTokens; i-- {
+		ctx.Trim.OmittedFiles = append(ctx.Trim.OmittedFiles, files[i].Path)
+		omitFile(ctx, files[i].Path)
+		if report, err = MeasureTokens(settings, name, tokenizer); err != nil {
+			return nil, err
+		}
+	}
+	for _, trimmer := range contextTrimmers {
+		if report.Total <= settings.MaxTokens {
+			break
+		}
+		if !trimmer.trim(ctx) {
+			continue
+		}
+		ctx.Trim.OmittedSections = append(ctx.Trim.OmittedSections, trimmer.section)
+		if report, err = MeasureTokens(settings, name, tokenizer); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// omitFile removes an omitted file from the context sections that list it, so
+// the prompt does not point the model at a file it is told not to review
+func omitFile(ctx *ReviewContext, p string) {
+	if o := ctx.Overview; o != nil {
+		for i, f := range o.Files {
+			if f.Path != p {
+				continue
+			}
+			o.Files = append(o.Files[:i:i], o.Files[i+1:]...)
+			if i < maxOverviewRows {
+				// The file left the table: count it as not listed, and stop
+				// counting the file that moves up into the table
+				o.OmittedFiles++
+				o.OmittedChurn += f.Churn
+				if len(o.Files) >= maxOverviewRows {
+					o.OmittedFiles--
+					o.OmittedChurn -= o.Files[maxOverviewRows-1].Churn
+				}
+			}
+			break
+		}
+	}
+
+	var missing []MissingTest
+	for _, m := range ctx.MissingTests {
+		if m.Path != p {
+			missing = append(missing, m)
+		}
+	}
+	ctx.MissingTests = missing
+
+	if c := ctx.Coverage; c != nil {
+		var uncovered []UncoveredFile
+		for _, u := range c.Files {
+			if u.Path == p {
+				c.Uncovered -= len(u.Lines)
+				c.Executable -= u.Executable
+				continue
+			}
+			uncovered = append(uncovered, u)
+		}
+		c.Files = uncovered
+		if c.Executable <= 0 {
+			ctx.Coverage = nil
+		}
+	}
+
+	if t := ctx.ToolFindings; t != nil {
+		var findings []ToolFinding
+		for _, f := range t.Findings {
+			if f.Path != p {
+				findings = append(findings, f)
+			}
+		}
+		t.Findings = findings
+		if len(findings) == 0 && t.Omitted == 0 {
+			ctx.ToolFindings = nil
+		}
+	}
+
+	var functions []FunctionContext
+	for _, f := range ctx.Functions {
+		if f.Path != p {
+			functions = append(functions, f)
+		}
+	}
+	ctx.Functions = functions
+
+	if c := ctx.Callers; c != nil {
+		var changed []ChangedFunction
+		for _, f := range c.Functions {
+			if f.Path != p {
+				changed = append(changed, f)
+			}
+		}
+		c.Functions = changed
+		if len(changed) == 0 && c.Omitted == 0 {
+			ctx.Callers = nil
+		}
+	}
+
+	if m := ctx.Migrations; m != nil {
+		var migrations []MigrationFile
+		for _, f := range m.Files {
+			if f.Path != p {
+				migrations = append(migrations, f)
+			}
+		}
+		m.Files = migrations
+		if len(migrations) == 0 {
+			ctx.Migrations = nil
+		}
+	}
+}
+
+// printTokenReport displays the estimated prompt size below the banner
+func printTokenReport(w io.Writer, report *TokenReport) {
+	fmt.Fprintf(w, "Prompt Size (%s estimate)\n", report.Tokenizer)
+	fmt.Fprintln(w, "======================")
+	fmt.Fprintf(w, "Prompt Tokens: %d\n", report.Prompt)
+	fmt.Fprintf(w, "Diff Tokens: %d\n", report.Diff)
+	if report.MaxTokens > 0 {
+		fmt.Fprintf(w, "Total Tokens: %d of %d\n", report.Total, report.MaxTokens)
+	} else {
+		fmt.Fprintf(w, "Total Tokens: %d\n", report.Total)
+	}
+	for _, s := range report.Sections {
+		fmt.Fprintf(w, "  %s: %d\n", s.Name, s.Tokens)
+	}
+	if t := report.Trimmed; t != nil {
+		if len(t.OmittedFiles) > 0 {
+			fmt.Fprintf(w, "Omitted Files: %s\n", t.FileList())
+		}
+		if len(t.OmittedSections) > 0 {
+			fmt.Fprintf(w, "Omitted Sections: %s\n", t.SectionList())
+		}
+	}
+	if report.MaxTokens > 0 && report.Total > report.MaxTokens {
+		fmt.Fprintln(w, "Warning: the prompt and diff still exceed max_tokens after trimming")
+	}
+	fmt.Fprintln(w, "======================")
+}
@@ -0,0 +1,297 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupTokenizer(t *testing.T) {
+	if tok, err := LookupTokenizer(""); err != nil || tok != (bpeTokenizer{}) {
+		t.Errorf("LookupTokenizer(\"\") = %v, %v, want the bpe tokenizer", tok, err)
+	}
+	if _, err := LookupTokenizer("tiktoken"); err == nil || !strings.Contains(err.Error(), "bpe, chars") {
+		t.Errorf("LookupTokenizer(tiktoken) error = %v, want the known tokenizers listed", err)
+	}
+
+	RegisterTokenizer("words", wordTokenizer{})
+	defer delete(tokenizers, "words")
+	if tok, err := LookupTokenizer("words"); err != nil || tok.CountTokens("a b c") != 3 {
+		t.Errorf("LookupTokenizer(words) = %v, %v, want the registered tokenizer", tok, err)
+	}
+}
+
+// wordTokenizer counts whitespace-separated words
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestBPETokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"ReviewOutputFile", 3},
+		{"internationalization", 3},
+		{"1234567", 3},
+		{"if err != nil {", 5},
+		{"\n\n\n", 1},
+		{"        return", 2},
+		{"データ", 3},
+	}
+	for _, tt := range tests {
+		if got := (bpeTokenizer{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+
+	if got := (charTokenizer{}).CountTokens("hello world"); got != 3 {
+		t.Errorf("charTokenizer.CountTokens() = %d, want 3", got)
+	}
+}
+
+func TestTrimReportPathspec(t *testing.T) {
+	trim := TrimReport{OmittedFiles: []string{"docs/a.md", "it's.txt"}}
+	if got, want := trim.Pathspec(), ` -- . ':(exclude)docs/a.md' ':(exclude)it'\''s.txt'`; got != want {
+		t.Errorf("Pathspec() = %s, want %s", got, want)
+	}
+	if got := (TrimReport{}).Pathspec(); got != "" {
+		t.Errorf("Pathspec() = %q, want empty without omitted files", got)
+	}
+}
+
+// budgetSettings returns settings whose context has a risky and a harmless file
+func budgetSettings() Settings {
+	added := func(path string, n int) FileDiff {
+		hunk := Hunk{OldStart: 1, NewStart: 1}
+		for i := 1; i <= n; i++ {
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: '+', NewLine: i, Text: "some added line of text"})
+		}
+		return FileDiff{Path: path, Hunks: []Hunk{hunk}}
+	}
+	return Settings{
+		RepoName:     "test-repo",
+		MergeBaseSha: "abc",
+		SourceSha:    "def",
+		CommentCount: 10,
+		Tokenizer:    "chars",
+		Context: &ReviewContext{
+			Files: []ChangedFile{
+				{Path: "auth/login.go", Status: "M", Additions: 40},
+				{Path: "docs/notes.md", Status: "M", Additions: 40},
+			},
+			Diffs:   []FileDiff{added("auth/login.go", 40), added("docs/notes.md", 40)},
+			Callers: &CallerReview{Functions: []ChangedFunction{{Name: "Login", Path: "auth/login.go", Line: 3, Callers: []CallSite{{Path: "api/api.go", Line: 9, Caller: "Handle", Snippet: "Login(user)"}}}}},
+		},
+	}
+}
+
+func TestMeasureTokens(t *testing.T) {
+	settings := budgetSettings()
+	report, err := MeasureTokens(settings, "chars", charTokenizer{})
+	if err != nil {
+		t.Fatalf("MeasureTokens() failed: %v", err)
+	}
+
+	prompt, err := RenderPrompt(settings)
+	if err != nil {
+		t.Fatalf("RenderPrompt() failed: %v", err)
+	}
+	if want := (charTokenizer{}).CountTokens(string(prompt)); report.Prompt != want {
+		t.Errorf("Prompt = %d, want %d", report.Prompt, want)
+	}
+	wantDiff := diffTokens(settings.Context.Diffs[0], charTokenizer{}) + diffTokens(settings.Context.Diffs[1], charTokenizer{})
+	if report.Diff != wantDiff || report.Total != report.Prompt+report.Diff {
+		t.Errorf("Diff = %d, Total = %d, want %d and the sum", report.Diff, report.Total, wantDiff)
+	}
+
+	sum := 0
+	names := []string{}
+	for _, s := range report.Sections {
+		sum += s.Tokens
+		names = append(names, s.Name)
+	}
+	if want := []string{"instructions", "callers"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Sections = %v, want %v", names, want)
+	}
+	if sum != report.Prompt {
+		t.Errorf("Sections add up to %d, want the prompt size %d", sum, report.Prompt)
+	}
+}
+
+func TestPromptSectionsListsEverySection(t *testing.T) {
+	listed := make(map[string]bool)
+	for _, section := range promptSections {
+		listed[section] = true
+	}
+	for _, version := range PromptTemplateNames() {
+		tmpl, err := parsePrompt(Settings{PromptTemplate: version})
+		if err != nil {
+			t.Fatalf("parsePrompt(%s) failed: %v", version, err)
+		}
+		for _, define := range tmpl.Templates() {
+			if name := define.Name(); name != "prompt" && name != "review_policy" && !listed[name] {
+				t.Errorf("promptSections should list %q of %s", name, version)
+			}
+		}
+		for _, section := range promptSections {
+			if tmpl.Lookup(section) == nil {
+				t.Errorf("%s does not define section %q", version, section)
+			}
+		}
+	}
+}
+
+func TestFitTokenBudget(t *testing.T) {
+	unlimited, err := FitTokenBudget(budgetSettings(), charTokenizer{})
+	if err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	lowRisk := diffTokens(budgetSettings().Context.Diffs[1], charTokenizer{})
+
+	// Omitting the harmless file is enough
+	settings := budgetSettings()
+	settings.MaxTokens = unlimited.Total - lowRisk/2
+	report, err := FitTokenBudget(settings, charTokenizer{})
+	if err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	want := &TrimReport{OmittedFiles: []string{"docs/notes.md"}}
+	if !reflect.DeepEqual(settings.Context.Trim, want) || !reflect.DeepEqual(report.Trimmed, want) {
+		t.Errorf("Trim = %+v, want %+v", settings.Context.Trim, want)
+	}
+	if report.Total > settings.MaxTokens {
+		t.Errorf("Total = %d, want at most %d", report.Total, settings.MaxTokens)
+	}
+	if settings.Context.Callers == nil {
+		t.Error("Context sections should be kept when omitting files is enough")
+	}
+
+	prompt, err := RenderPrompt(settings)
+	if err != nil {
+		t.Fatalf("RenderPrompt() failed: %v", err)
+	}
+	for _, expected := range []string{
+		"git diff --color=never abc...def -- . ':(exclude)docs/notes.md' | awk",
+		"The diff command above excludes these lower-risk files; do not review them: docs/notes.md.",
+	} {
+		if !strings.Contains(string(prompt), expected) {
+			t.Errorf("Prompt should contain: %s", expected)
+		}
+	}
+
+	// The riskiest file is always kept, then context is dropped
+	settings = budgetSettings()
+	settings.MaxTokens = 10
+	report, err = FitTokenBudget(settings, charTokenizer{})
+	if err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	want = &TrimReport{OmittedFiles: []string{"docs/notes.md"}, OmittedSections: []string{"callers"}}
+	if !reflect.DeepEqual(settings.Context.Trim, want) {
+		t.Errorf("Trim = %+v, want %+v", settings.Context.Trim, want)
+	}
+	if report.Total <= settings.MaxTokens {
+		t.Errorf("Total = %d, the budget cannot be met", report.Total)
+	}
+}
+
+func TestFitTokenBudgetDropsDanglingContext(t *testing.T) {
+	contextSettings := func() Settings {
+		settings := budgetSettings()
+		ctx := settings.Context
+		ctx.Overview = AnalyzeRisk(ctx.Files, ctx.Diffs)
+		ctx.MissingTests = []MissingTest{{Path: "docs/notes.md", Expected: []string{"docs/notes_test.md"}}}
+		ctx.Coverage = &CoverageReport{File: "cover.out", Format: "go", Executable: 8, Uncovered: 3, Files: []UncoveredFile{
+			{Path: "auth/login.go", Lines: []int{4}, Executable: 5},
+			{Path: "docs/notes.md", Lines: []int{1, 2}, Executable: 3},
+		}}
+		ctx.ToolFindings = &ToolFindings{Findings: []ToolFinding{{Path: "docs/notes.md", StartLine: 1, Tool: "vale", Message: "Passive voice"}}}
+		ctx.Callers.Functions = append(ctx.Callers.Functions, ChangedFunction{Name: "Notes", Path: "docs/notes.md", Line: 1})
+		ctx.PullRequest = &PullRequestInfo{Title: "Add login", Commits: []Commit{{SHA: "0123456789", Subject: "wip"}}}
+		ctx.Commits = &CommitReview{}
+		return settings
+	}
+	render := func(settings Settings) string {
+		prompt, err := RenderPrompt(settings)
+		if err != nil {
+			t.Fatalf("RenderPrompt() failed: %v", err)
+		}
+		return string(prompt)
+	}
+
+	// The omitted file is only named by the diff command and the budget note
+	unlimited, err := FitTokenBudget(contextSettings(), charTokenizer{})
+	if err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	settings := contextSettings()
+	settings.MaxTokens = unlimited.Total - diffTokens(settings.Context.Diffs[1], charTokenizer{})/2
+	if _, err := FitTokenBudget(settings, charTokenizer{}); err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	if want := []string{"docs/notes.md"}; !reflect.DeepEqual(settings.Context.Trim.OmittedFiles, want) || len(settings.Context.Trim.OmittedSections) != 0 {
+		t.Fatalf("Trim = %+v, want only %v omitted", settings.Context.Trim, want)
+	}
+	prompt := render(settings)
+	if n := strings.Count(prompt, "docs/notes.md"); n != 2 {
+		t.Errorf("Prompt names docs/notes.md %d times, want only the diff command and the budget note:\n%s", n, prompt)
+	}
+	for _, expected := range []string{
+		"1 lower-risk files with 40 changed lines are not listed.",
+		"1 of 5 changed executable lines were not run by any test.",
+		"- Login (auth/login.go:3):",
+	} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("Prompt should contain: %s", expected)
+		}
+	}
+	if settings.Context.ToolFindings != nil || settings.Context.MissingTests != nil {
+		t.Error("Sections left without entries should be dropped")
+	}
+
+	// The commit review is dropped before the commits it refers to
+	settings = contextSettings()
+	settings.MaxTokens = 10
+	if _, err := FitTokenBudget(settings, charTokenizer{}); err != nil {
+		t.Fatalf("FitTokenBudget() failed: %v", err)
+	}
+	if got := settings.Context.Trim.SectionList(); !strings.Contains(got, "commit_review, pull_request") {
+		t.Errorf("OmittedSections = %s, want commit_review before pull_request", got)
+	}
+	if prompt := render(settings); strings.Contains(prompt, "listed above") {
+		t.Errorf("Prompt should not refer to the omitted commits:\n%s", prompt)
+	}
+}
+
+func TestWritePromptReportsTokens(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := budgetSettings()
+	settings.OutputFile = filepath.Join(tempDir, "task.txt")
+	settings.ReviewOutputFile = filepath.Join(tempDir, "review.json")
+	settings.MaxTokens = 100000
+
+	var stdout, stderr strings.Builder
+	if err := writePrompt(settings, &stdout, &stderr); err != nil {
+		t.Fatalf("writePrompt() failed: %v", err)
+	}
+	for _, expected := range []string{"Prompt Size (chars estimate)", "Total Tokens: ", " of 100000\n", "  callers: "} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Status output should contain %q, got:\n%s", expected, stdout.String())
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if !strings.Contains(string(content), `"tokens": {`) || !strings.Contains(string(content), `"max_tokens": 100000`) {
+		t.Error("Manifest should contain the token report")
+	}
+}
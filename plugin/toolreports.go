@@ -0,0 +1,286 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// StaticAnalysisType is the review type of tool findings merged into the review output
+const StaticAnalysisType = "static_analysis"
+
+// maxToolFindings bounds the findings listed in the prompt
+const maxToolFindings = 100
+
+// ToolFinding is a warning reported by a linter or static analyzer
+type ToolFinding struct {
+	Tool      string `json:"tool"`
+	Rule      string `json:"rule,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Message   string `json:"message"`
+}
+
+// Source names the tool and rule that produced the finding, e.g. "golangci-lint errcheck"
+func (f ToolFinding) Source() string {
+	if f.Rule == "" {
+		return f.Tool
+	}
+	return f.Tool + " " + f.Rule
+}
+
+// ReviewComment converts the finding into an entry of the review output
+func (f ToolFinding) ReviewComment() ReviewComment {
+	return ReviewComment{
+		FilePath:        f.Path,
+		LineNumberStart: f.StartLine,
+		LineNumberEnd:   f.EndLine,
+		Type:            StaticAnalysisType,
+		Review:          fmt.Sprintf("**%s**: %s", f.Source(), f.Message),
+	}
+}
+
+// ToolFindings is the static analysis section of the prompt
+type ToolFindings struct {
+	Findings []ToolFinding `json:"findings"`
+	Omitted  int           `json:"omitted,omitempty"`
+}
+
+// sarifLog is the subset of a SARIF 2.1.0 log the plugin reads
+type sarifLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name string `json:"name"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+						EndLine   int `json:"endLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+// checkstyleReport is the checkstyle XML format emitted by golangci-lint, eslint and others
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+// ReadToolReport loads the findings of a SARIF or checkstyle report, detected from its content
+func ReadToolReport(file string) ([]ToolFinding, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tool report: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		findings, err := parseSARIF(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse SARIF report %s: %w", file, err)
+		}
+		return findings, nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		findings, err := parseCheckstyle(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse checkstyle report %s: %w", file, err)
+		}
+		return findings, nil
+	}
+	return nil, fmt.Errorf("tool report %s is neither SARIF nor checkstyle XML", file)
+}
+
+// parseSARIF extracts the results of every run of a SARIF log
+func parseSARIF(content []byte) ([]ToolFinding, error) {
+	var log sarifLog
+	if err := json.Unmarshal(content, &log); err != nil {
+		return nil, err
+	}
+
+	var findings []ToolFinding
+	for _, run := range log.Runs {
+		for _, result := range run.Results {
+			for _, location := range result.Locations {
+				physical := location.PhysicalLocation
+				f := ToolFinding{
+					Tool:      run.Tool.Driver.Name,
+					Rule:      result.RuleID,
+					Severity:  result.Level,
+					Path:      sarifPath(physical.ArtifactLocation.URI),
+					StartLine: physical.Region.StartLine,
+					EndLine:   physical.Region.EndLine,
+					Message:   result.Message.Text,
+				}
+				if f.EndLine < f.StartLine {
+					f.EndLine = f.StartLine
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// sarifPath converts an artifact URI into a file path
+func sarifPath(uri string) string {
+	if u, err := url.Parse(uri); err == nil && (u.Scheme == "file" || u.Scheme == "") {
+		return strings.TrimPrefix(u.Path, "./")
+	}
+	return uri
+}
+
+// parseCheckstyle extracts the errors of a checkstyle XML report
+func parseCheckstyle(content []byte) ([]ToolFinding, error) {
+	var report checkstyleReport
+	if err := xml.Unmarshal(content, &report); err != nil {
+		return nil, err
+	}
+
+	var findings []ToolFinding
+	for _, file := range report.Files {
+		for _, e := range file.Errors {
+			// Sources look like "errcheck" (golangci-lint) or "eslint.rules.no-unused-vars"
+			tool, rule := e.Source, ""
+			if t, r, ok := strings.Cut(e.Source, ".rules."); ok {
+				tool, rule = t, r
+			}
+			if tool == "" {
+				tool = "checkstyle"
+			}
+			findings = append(findings, ToolFinding{
+				Tool:      tool,
+				Rule:      rule,
+				Severity:  e.Severity,
+				Path:      strings.TrimPrefix(file.Name, "./"),
+				StartLine: e.Line,
+				EndLine:   e.Line,
+				Message:   e.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// CollectToolFindings reads the comma-separated reports and keeps the findings on
+// added lines. Unreadable reports are returned as warnings.
+func CollectToolFindings(reports string, diffs []FileDiff) ([]ToolFinding, []string) {
+	var findings []ToolFinding
+	var warnings []string
+	for _, report := range strings.Split(reports, ",") {
+		report = strings.TrimSpace(report)
+		if report == "" {
+			continue
+		}
+		f, err := ReadToolReport(report)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		findings = append(findings, f...)
+	}
+	return FilterToolFindings(findings, diffs), warnings
+}
+
+// FilterToolFindings keeps the findings whose line range touches an added line,
+// rewriting report paths to repository paths
+func FilterToolFindings(findings []ToolFinding, diffs []FileDiff) []ToolFinding {
+	added := make(map[string]map[int]bool)
+	var paths []string
+	for _, d := range diffs {
+		lines := make(map[int]bool)
+		for _, l := range d.AddedLines() {
+			lines[l] = true
+		}
+		added[d.Path] = lines
+		paths = append(paths, d.Path)
+	}
+
+	var result []ToolFinding
+	for _, f := range findings {
+		repoPath := resolveReportPath(f.Path, paths)
+		if repoPath == "" {
+			continue
+		}
+		for l := f.StartLine; l <= f.EndLine; l++ {
+			if added[repoPath][l] {
+				f.Path = repoPath
+				result = append(result, f)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].StartLine < result[j].StartLine
+	})
+	return result
+}
+
+// resolveReportPath maps a path from a tool report, which may be absolute or
+// relative to another root, to the changed repository path it names. When
+// several changed paths match, the longest one is the most specific.
+func resolveReportPath(reported string, paths []string) string {
+	match := ""
+	for _, p := range paths {
+		if (reported == p || strings.HasSuffix(reported, "/"+p)) && len(p) > len(match) {
+			match = p
+		}
+	}
+	return match
+}
+
+// NewToolFindings builds the prompt section, listing at most maxToolFindings findings
+func NewToolFindings(findings []ToolFinding) *ToolFindings {
+	if len(findings) == 0 {
+		return nil
+	}
+	section := &ToolFindings{Findings: findings}
+	if len(findings) > maxToolFindings {
+		section.Findings = findings[:maxToolFindings]
+		section.Omitted = len(findings) - maxToolFindings
+	}
+	return section
+}
+
+// MergeToolFindings appends the findings to the review output, skipping those
+// already reported at the same location with the same text
+func MergeToolFindings(output *ReviewOutput, findings []ToolFinding) int {
+	comments := make([]ReviewComment, 0, len(findings))
+	for _, f := range findings {
+		comments = append(comments, f.ReviewComment())
+	}
+	return output.AddComments(comments)
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSARIF = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "golangci-lint"}},
+    "results": [
+      {
+        "ruleId": "errcheck",
+        "level": "error",
+        "message": {"text": "Error return value is not checked"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///drone/src/plugin/cli.go"}, "region": {"startLine": 11}}}]
+      },
+      {
+        "ruleId": "unused",
+        "level": "warning",
+        "message": {"text": "func helper is unused"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "plugin/cli.go"}, "region": {"startLine": 3, "endLine": 5}}}]
+      }
+    ]
+  }]
+}`
+
+const testCheckstyle = `<?xml version="1.0" encoding="utf-8"?>
+<checkstyle version="4.3">
+  <file name="/work/web/app.ts">
+    <error line="8" column="3" severity="warning" message="'x' is assigned a value but never used." source="eslint.rules.no-unused-vars" />
+    <error line="40" column="1" severity="error" message="Unexpected any." source="eslint.rules.no-explicit-any" />
+  </file>
+  <file name="plugin/cli.go">
+    <error line="20" column="2" severity="error" message="ineffectual assignment to err" source="ineffassign" />
+  </file>
+</checkstyle>`
+
+// toolReportDiffs adds lines 10-12 and 20 to plugin/cli.go and line 8 to web/app.ts
+var toolReportDiffs = []FileDiff{
+	{Path: "plugin/cli.go", Hunks: []Hunk{{Lines: []DiffLine{
+		{Kind: '+', NewLine: 10}, {Kind: '+', NewLine: 11}, {Kind: '+', NewLine: 12}, {Kind: ' ', OldLine: 13, NewLine: 13},
+	}}, {Lines: []DiffLine{{Kind: '+', NewLine: 20}}}}},
+	{Path: "web/app.ts", Hunks: []Hunk{{Lines: []DiffLine{{Kind: '+', NewLine: 8}}}}},
+}
+
+func writeToolReport(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write tool report: %v", err)
+	}
+	return file
+}
+
+func TestReadToolReport(t *testing.T) {
+	findings, err := ReadToolReport(writeToolReport(t, "lint.sarif", testSARIF))
+	if err != nil {
+		t.Fatalf("ReadToolReport(SARIF) failed: %v", err)
+	}
+	want := []ToolFinding{
+		{Tool: "golangci-lint", Rule: "errcheck", Severity: "error", Path: "/drone/src/plugin/cli.go", StartLine: 11, EndLine: 11, Message: "Error return value is not checked"},
+		{Tool: "golangci-lint", Rule: "unused", Severity: "warning", Path: "plugin/cli.go", StartLine: 3, EndLine: 5, Message: "func helper is unused"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("ReadToolReport(SARIF) =\n%+v\nwant\n%+v", findings, want)
+	}
+
+	findings, err = ReadToolReport(writeToolReport(t, "checkstyle.xml", testCheckstyle))
+	if err != nil {
+		t.Fatalf("ReadToolReport(checkstyle) failed: %v", err)
+	}
+	if len(findings) != 3 {
+		t.Fatalf("ReadToolReport(checkstyle) returned %d findings, want 3", len(findings))
+	}
+	if f := findings[0]; f.Tool != "eslint" || f.Rule != "no-unused-vars" || f.Path != "/work/web/app.ts" || f.StartLine != 8 {
+		t.Errorf("eslint finding = %+v", f)
+	}
+	if f := findings[2]; f.Tool != "ineffassign" || f.Rule != "" || f.Source() != "ineffassign" {
+		t.Errorf("golangci-lint finding = %+v", f)
+	}
+
+	for name, content := range map[string]string{"bad.sarif": "{not json", "bad.xml": "<checkstyle><file>", "bad.txt": "plain text"} {
+		if _, err := ReadToolReport(writeToolReport(t, name, content)); err == nil {
+			t.Errorf("ReadToolReport(%s) should fail", name)
+		}
+	}
+}
+
+func TestCollectToolFindings(t *testing.T) {
+	reports := writeToolReport(t, "lint.sarif", testSARIF) + ", " + writeToolReport(t, "eslint.xml", testCheckstyle) + ",missing.sarif"
+	findings, warnings := CollectToolFindings(reports, toolReportDiffs)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "could not read tool report") {
+		t.Errorf("warnings = %q, want one for the missing report", warnings)
+	}
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Path+":"+f.Rule)
+	}
+	want := []string{"plugin/cli.go:errcheck", "plugin/cli.go:", "web/app.ts:no-unused-vars"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectToolFindings() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveReportPath(t *testing.T) {
+	for _, paths := range [][]string{{"util.go", "b/util.go"}, {"b/util.go", "util.go"}} {
+		if got := resolveReportPath("/work/a/b/util.go", paths); got != "b/util.go" {
+			t.Errorf("resolveReportPath(%v) = %q, want the longest match b/util.go", paths, got)
+		}
+	}
+	if got := resolveReportPath("/work/a/c/util.go", []string{"b/util.go"}); got != "" {
+		t.Errorf("resolveReportPath() = %q, want no match", got)
+	}
+}
+
+func TestNewToolFindings(t *testing.T) {
+	if NewToolFindings(nil) != nil {
+		t.Error("NewToolFindings(nil) should be nil")
+	}
+	findings := make([]ToolFinding, maxToolFindings+5)
+	section := NewToolFindings(findings)
+	if len(section.Findings) != maxToolFindings || section.Omitted != 5 {
+		t.Errorf("NewToolFindings() kept %d and omitted %d, want %d and 5", len(section.Findings), section.Omitted, maxToolFindings)
+	}
+}
+
+func TestMergeToolFindings(t *testing.T) {
+	finding := ToolFinding{Tool: "golangci-lint", Rule: "errcheck", Path: "a.go", StartLine: 3, EndLine: 3, Message: "unchecked error"}
+	output := ReviewOutput{Reviews: []ReviewComment{
+		{FilePath: "a.go", LineNumberStart: 1, LineNumberEnd: 1, Type: "bug", Review: "nil dereference"},
+		finding.ReviewComment(),
+	}}
+	other := ToolFinding{Tool: "eslint", Path: "b.ts", StartLine: 7, EndLine: 7, Message: "no any"}
+
+	if merged := MergeToolFindings(&output, []ToolFinding{finding, other, other}); merged != 1 {
+		t.Errorf("MergeToolFindings() = %d, want 1", merged)
+	}
+	if len(output.Reviews) != 3 {
+		t.Fatalf("Reviews = %+v, want 3 comments", output.Reviews)
+	}
+	if got := output.Reviews[2]; got.Type != StaticAnalysisType || got.Review != "**eslint**: no any" {
+		t.Errorf("merged comment = %+v", got)
+	}
+}
@@ -0,0 +1,337 @@
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// What publish does with suggestions that fail verification
+const (
+	verifyOff      = "off"
+	verifyAnnotate = "annotate"
+	verifyDrop     = "drop"
+)
+
+// Outcomes of a suggestion check
+const (
+	checkPassed = "passed"
+	checkFailed = "failed"
+)
+
+// maxCheckOutput is the number of lines of go build or go vet output kept per check
+const maxCheckOutput = 10
+
+// suggestionChecks are the checks a suggestion can go through, in the order they run
+var suggestionChecks = []string{"go/parser", "gofmt", "go build", "go vet"}
+
+// SuggestionCheck records the verification of the suggestion blocks of a comment
+type SuggestionCheck struct {
+	Status string `json:"status"`
+	// Checks lists the checks that ran on at least one of the blocks
+	Checks []string `json:"checks"`
+	Errors []string `json:"errors,omitempty"`
+	// Dropped is set when the failing suggestion blocks were removed from the review
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// SuggestionVerification summarizes a VerifySuggestions run
+type SuggestionVerification struct {
+	Checked  int
+	Failed   int
+	Warnings []string
+}
+
+// VerifySuggestions checks every suggestion block on a Go file by applying it
+// alone to the file at sha, or in the working tree when sha is empty, then
+// parsing the result with go/parser and comparing it with its gofmt output.
+// With build, the package of the file is also built and vetted offline in a
+// scratch copy of sha. Checks that fail on the unchanged file are skipped, so
+// a suggestion is only blamed for the problems it introduces.
+//
+// Each checked comment records a SuggestionCheck. A failing comment gets a
+// note in its review text, or with mode drop loses its failing blocks.
+// Comments that already carry a check are left alone.
+func VerifySuggestions(dir, sha string, output *ReviewOutput, mode string, build bool) SuggestionVerification {
+	v := &suggestionVerifier{dir: dir, sha: sha, build: build, baselines: make(map[string]string)}
+	defer v.cleanup()
+
+	var result SuggestionVerification
+	for i := range output.Reviews {
+		r := &output.Reviews[i]
+		_, blocks := ExtractSuggestions(r.Review)
+		if len(blocks) == 0 || r.SuggestionCheck != nil || !strings.HasSuffix(r.FilePath, ".go") {
+			continue
+		}
+		original, err := v.source(r.FilePath)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("could not verify the suggestions of review %d: %v", i, err))
+			continue
+		}
+
+		check := &SuggestionCheck{Status: checkPassed}
+		failing := make(map[int]bool)
+		ran := make(map[string]bool)
+		for j, block := range blocks {
+			errs := v.verify(r.FilePath, original, r.LineNumberStart, r.LineNumberEnd, block, ran)
+			if len(errs) == 0 {
+				continue
+			}
+			failing[j] = true
+			for _, e := range errs {
+				if len(blocks) > 1 {
+					e = fmt.Sprintf("suggestion %d: %s", j+1, e)
+				}
+				check.Errors = append(check.Errors, e)
+			}
+		}
+		check.Checks = []string{}
+		for _, name := range suggestionChecks {
+			if ran[name] {
+				check.Checks = append(check.Checks, name)
+			}
+		}
+		result.Checked++
+		if len(failing) > 0 {
+			result.Failed++
+			check.Status = checkFailed
+			if mode == verifyDrop {
+				r.Review = removeSuggestions(r.Review, failing)
+				check.Dropped = true
+			} else {
+				r.Review += "\n\n> **Note:** the suggested change failed verification:\n> " +
+					strings.ReplaceAll(strings.Join(check.Errors, "\n"), "\n", "\n> ")
+			}
+		}
+		r.SuggestionCheck = check
+	}
+	result.Warnings = append(result.Warnings, v.warnings...)
+	return result
+}
+
+// suggestionVerifier applies suggestions to Go files and checks the result
+type suggestionVerifier struct {
+	dir   string
+	sha   string
+	build bool
+	// scratch is the copy of sha that packages are built in, created on first use
+	scratch  string
+	warnings []string
+	// baselines holds the go build and go vet failures of unchanged packages,
+	// keyed by "build <dir>" and "vet <dir>"
+	baselines map[string]string
+}
+
+// source reads a file at sha, or from the working tree when sha is empty
+func (v *suggestionVerifier) source(filePath string) (string, error) {
+	if v.sha == "" {
+		content, err := os.ReadFile(filepath.Join(v.dir, filepath.FromSlash(filePath)))
+		return string(content), err
+	}
+	content, err := runGit(v.dir, "show", v.sha+":"+filePath)
+	return string(content), err
+}
+
+// verify applies one suggestion to lines start to end of original and returns
+// the problems it introduces. The checks it runs are added to ran.
+func (v *suggestionVerifier) verify(filePath, original string, start, end int, suggestion []string, ran map[string]bool) []string {
+	lines := strings.Split(strings.TrimSuffix(original, "\n"), "\n")
+	if start < 1 || end > len(lines) {
+		return []string{fmt.Sprintf("lines %d-%d are outside the file, which has %d lines", start, end, len(lines))}
+	}
+	patched := append(append(append([]string{}, lines[:start-1]...), suggestion...), lines[end:]...)
+	content := strings.Join(patched, "\n") + "\n"
+
+	if _, err := parser.ParseFile(token.NewFileSet(), filePath, original, parser.AllErrors); err == nil {
+		ran["go/parser"] = true
+		if _, err := parser.ParseFile(token.NewFileSet(), filePath, content, parser.AllErrors); err != nil {
+			return []string{err.Error()}
+		}
+	}
+	var errs []string
+	if formatted, err := format.Source([]byte(original)); err == nil && string(formatted) == original {
+		ran["gofmt"] = true
+		if formatted, err := format.Source([]byte(content)); err == nil && string(formatted) != content {
+			errs = append(errs, "the suggested code is not gofmt-formatted")
+		}
+	}
+	if v.build {
+		errs = append(errs, v.buildPackage(filePath, original, content, ran)...)
+	}
+	return errs
+}
+
+// buildPackage runs go build and go vet on the package of filePath in the
+// scratch copy with content in place of the file, then restores the original.
+// The tools it runs are added to ran.
+func (v *suggestionVerifier) buildPackage(filePath, original, content string, ran map[string]bool) []string {
+	if err := v.prepareScratch(); err != nil {
+		return nil
+	}
+	pkg := path.Dir(filePath)
+	target := filepath.Join(v.scratch, filepath.FromSlash(filePath))
+	for _, tool := range []string{"build", "vet"} {
+		key := tool + " " + pkg
+		if _, ok := v.baselines[key]; !ok {
+			v.baselines[key] = v.runGo(pkg, tool)
+			if v.baselines[key] != "" {
+				v.warnings = append(v.warnings, fmt.Sprintf("skipped go %s of suggestions in %s, which fails without them: %s", tool, pkg, firstLine(v.baselines[key])))
+			}
+		}
+	}
+
+	if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+		return []string{fmt.Sprintf("failed to write scratch copy: %v", err)}
+	}
+	defer os.WriteFile(target, []byte(original), 0644)
+	var errs []string
+	for _, tool := range []string{"build", "vet"} {
+		if v.baselines[tool+" "+pkg] != "" {
+			continue
+		}
+		ran["go "+tool] = true
+		if out := v.runGo(pkg, tool); out != "" {
+			errs = append(errs, "go "+tool+": "+out)
+		}
+	}
+	return errs
+}
+
+// runGo runs go build or go vet offline on a package of the scratch copy and
+// returns its output when it fails
+func (v *suggestionVerifier) runGo(pkg, tool string) string {
+	args := []string{tool}
+	if tool == "build" {
+		args = append(args, "-o", os.DevNull)
+	}
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = filepath.Join(v.scratch, filepath.FromSlash(pkg))
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOTOOLCHAIN=local", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return err.Error()
+	}
+	if len(lines) > maxCheckOutput {
+		lines = append(lines[:maxCheckOutput], fmt.Sprintf("(%d more lines)", len(lines)-maxCheckOutput))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prepareScratch extracts sha into a temporary directory the first time it is needed
+func (v *suggestionVerifier) prepareScratch() error {
+	if v.scratch != "" {
+		return nil
+	}
+	err := v.extract()
+	if err != nil {
+		v.warnings = append(v.warnings, fmt.Sprintf("skipped go build and go vet of suggestions: %v", err))
+		v.build = false
+	}
+	return err
+}
+
+// extract writes the tree of sha into a new scratch directory
+func (v *suggestionVerifier) extract() error {
+	if v.sha == "" {
+		return errors.New("source_sha is required")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return err
+	}
+	archive, err := runGit(v.dir, "archive", "--format=tar", v.sha)
+	if err != nil {
+		return err
+	}
+	scratch, err := os.MkdirTemp("", "suggestions-")
+	if err != nil {
+		return err
+	}
+	v.scratch = scratch
+
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive of %s: %w", v.sha, err)
+		}
+		target := filepath.Join(scratch, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, scratch+string(filepath.Separator)) {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			var content []byte
+			if content, err = io.ReadAll(reader); err == nil {
+				err = os.WriteFile(target, content, 0644)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+	}
+}
+
+// cleanup removes the scratch copy
+func (v *suggestionVerifier) cleanup() {
+	if v.scratch != "" {
+		os.RemoveAll(v.scratch)
+	}
+}
+
+// removeSuggestions removes the suggestion blocks whose indexes are in drop from a review text
+func removeSuggestions(review string, drop map[int]bool) string {
+	var kept []string
+	block := -1
+	inBlock := false
+	for _, line := range strings.Split(review, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inBlock && strings.HasPrefix(trimmed, "```suggestion"):
+			inBlock = true
+			block++
+			if drop[block] {
+				continue
+			}
+		case inBlock && trimmed == "```":
+			inBlock = false
+			if drop[block] {
+				continue
+			}
+		case inBlock && drop[block]:
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// firstLine returns the first line of text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// verifyRepo commits a small Go module and returns the repository and commit
+func verifyRepo(t *testing.T) (*testRepo, string) {
+	repo := newTestRepo(t)
+	repo.write("go.mod", "module example.com/demo\n\ngo 1.21\n")
+	repo.write("calc/calc.go", "package calc\n\n// Add returns the sum of a and b\nfunc Add(a, b int) int {\n\treturn a + b\n}\n")
+	repo.write("README.md", "# Demo\n")
+	return repo, repo.commit("initial")
+}
+
+func TestVerifySuggestions(t *testing.T) {
+	repo, sha := verifyRepo(t)
+	output := ReviewOutput{Reviews: []ReviewComment{
+		suggest("calc/calc.go", 5, 5, "\treturn a + b + 0"),
+		suggest("calc/calc.go", 5, 5, "\treturn a +"),
+		suggest("calc/calc.go", 5, 5, "    return a+b"),
+		suggest("README.md", 1, 1, "# Demo app"),
+		{FilePath: "calc/calc.go", LineNumberStart: 4, LineNumberEnd: 4, Review: "Fine."},
+	}}
+
+	result := VerifySuggestions(repo.dir, sha, &output, verifyAnnotate, false)
+	if result.Checked != 3 || result.Failed != 2 || len(result.Warnings) != 0 {
+		t.Errorf("VerifySuggestions() = %+v, want 3 checked and 2 failed", result)
+	}
+
+	passed := output.Reviews[0].SuggestionCheck
+	if passed == nil || passed.Status != checkPassed || strings.Join(passed.Checks, ",") != "go/parser,gofmt" {
+		t.Errorf("SuggestionCheck = %+v, want passed", passed)
+	}
+	if strings.Contains(output.Reviews[0].Review, "Note") {
+		t.Error("A passing suggestion should not be annotated")
+	}
+
+	broken := output.Reviews[1]
+	if broken.SuggestionCheck == nil || broken.SuggestionCheck.Status != checkFailed || !strings.Contains(broken.SuggestionCheck.Errors[0], "calc/calc.go:6:1: expected operand") || strings.Join(broken.SuggestionCheck.Checks, ",") != "go/parser" {
+		t.Errorf("SuggestionCheck = %+v, want the parse error", broken.SuggestionCheck)
+	}
+	if !strings.Contains(broken.Review, "```\n\n> **Note:** the suggested change failed verification:\n> calc/calc.go:6:1") {
+		t.Errorf("Review should carry the note, got:\n%s", broken.Review)
+	}
+	if errs := output.Reviews[2].SuggestionCheck.Errors; len(errs) != 1 || errs[0] != "the suggested code is not gofmt-formatted" {
+		t.Errorf("Errors = %q, want the gofmt failure", errs)
+	}
+	if output.Reviews[3].SuggestionCheck != nil || output.Reviews[4].SuggestionCheck != nil {
+		t.Error("Only Go suggestions should be checked")
+	}
+
+	// Checked comments are not checked again
+	if again := VerifySuggestions(repo.dir, sha, &output, verifyAnnotate, false); again.Checked != 0 {
+		t.Errorf("VerifySuggestions() checked %d comments again", again.Checked)
+	}
+}
+
+func TestVerifySuggestionsDrop(t *testing.T) {
+	repo, sha := verifyRepo(t)
+	review := "Two options.\n```suggestion\n\treturn b + a\n```\nor\n```suggestion\n\treturn a +\n```"
+	output := ReviewOutput{Reviews: []ReviewComment{{FilePath: "calc/calc.go", LineNumberStart: 5, LineNumberEnd: 5, Type: "code_smell", Review: review}}}
+
+	VerifySuggestions(repo.dir, sha, &output, verifyDrop, false)
+	check := output.Reviews[0].SuggestionCheck
+	if check == nil || !check.Dropped || len(check.Errors) != 1 || !strings.HasPrefix(check.Errors[0], "suggestion 2: ") {
+		t.Errorf("SuggestionCheck = %+v, want the second suggestion dropped", check)
+	}
+	if want := "Two options.\n```suggestion\n\treturn b + a\n```\nor"; output.Reviews[0].Review != want {
+		t.Errorf("Review = %q, want %q", output.Reviews[0].Review, want)
+	}
+}
+
+func TestVerifySuggestionsBuildSkipped(t *testing.T) {
+	repo, _ := verifyRepo(t)
+	output := ReviewOutput{Reviews: []ReviewComment{suggest("calc/calc.go", 5, 5, "\treturn b + a")}}
+
+	// Without a SHA there is no scratch copy to build in
+	result := VerifySuggestions(repo.dir, "", &output, verifyAnnotate, true)
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "skipped go build and go vet") {
+		t.Errorf("Warnings = %q, want the skipped build reported", result.Warnings)
+	}
+	if check := output.Reviews[0].SuggestionCheck; strings.Join(check.Checks, ",") != "go/parser,gofmt" {
+		t.Errorf("Checks = %q, want only the checks that ran", check.Checks)
+	}
+}
+
+func TestVerifySuggestionsBuild(t *testing.T) {
+	repo, sha := verifyRepo(t)
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not available")
+	}
+	t.Setenv("GOCACHE", filepath.Join(t.TempDir(), "cache"))
+	output := ReviewOutput{Reviews: []ReviewComment{
+		suggest("calc/calc.go", 5, 5, "\treturn a + c"),
+		suggest("calc/calc.go", 5, 5, "\treturn b + a"),
+	}}
+
+	result := VerifySuggestions(repo.dir, sha, &output, verifyAnnotate, true)
+	if result.Failed != 1 || len(result.Warnings) != 0 {
+		t.Fatalf("VerifySuggestions() = %+v, want one failure", result)
+	}
+	check := output.Reviews[0].SuggestionCheck
+	if len(check.Checks) != 4 || len(check.Errors) != 2 || !strings.Contains(check.Errors[0], "go build: ./calc.go:5:13: undefined: c") || !strings.HasPrefix(check.Errors[1], "go vet: ") {
+		t.Errorf("SuggestionCheck = %+v, want go build and go vet failures", check)
+	}
+	if output.Reviews[1].SuggestionCheck.Status != checkPassed {
+		t.Errorf("SuggestionCheck = %+v, want passed", output.Reviews[1].SuggestionCheck)
+	}
+}
+
+func TestRunPublishVerifiesSuggestions(t *testing.T) {
+	os.Clearenv()
+	tempDir := t.TempDir()
+	reviewFile := filepath.Join(tempDir, "review.json")
+	suggestion := "x\\n```suggestion\\npackage plugin\\n```"
+	review := `{"reviews": [
+		{"file_path": "gone.go", "line_number_start": 1, "line_number_end": 1, "type": "bug", "review": "` + suggestion + `"},
+		{"file_path": "version.go", "line_number_start": 1, "line_number_end": 1, "type": "bug", "review": "` + suggestion + `"}
+	]}`
+	if err := os.WriteFile(reviewFile, []byte(review), 0644); err != nil {
+		t.Fatalf("Failed to write review file: %v", err)
+	}
+
+	// Turning verification off leaves the review output alone
+	var stdout, stderr strings.Builder
+	if code := Run([]string{"publish", "-review-output-file", reviewFile, "-output-vars-file", "", "-verify-suggestions", "off"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if content, _ := os.ReadFile(reviewFile); string(content) != review || strings.Contains(stdout.String(), "Verified") {
+		t.Errorf("publish should not verify suggestions when off, got:\n%s\n%s", stdout.String(), content)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"publish", "-review-output-file", reviewFile, "-output-vars-file", ""}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, want 0\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Warning: could not verify the suggestions of review 0") {
+		t.Errorf("publish should warn about unverifiable suggestions, got:\n%s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Verified the suggestions of 1 comments: 0 failed") {
+		t.Errorf("publish should report the verified suggestions, got:\n%s", stdout.String())
+	}
+
+	output, err := ReadReviewOutput(reviewFile)
+	if err != nil {
+		t.Fatalf("ReadReviewOutput() failed: %v", err)
+	}
+	if output.Reviews[0].SuggestionCheck != nil || output.Reviews[1].SuggestionCheck == nil || output.Reviews[1].SuggestionCheck.Status != checkPassed {
+		t.Errorf("Review output should record the check of the working tree file, got %+v", output.Reviews)
+	}
+}
@@ -0,0 +1,5 @@
+package plugin
+
+// Version is the plugin version, overridden at build time with
+// -ldflags "-X github.com/abhinav-harness/ai-review-prompt-plugin/plugin.Version=..."
+var Version = "dev"
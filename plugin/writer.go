@@ -1,41 +1,192 @@
 package plugin
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"text/template"
 )
 
+// RenderPrompt executes the prompt template with settings into memory
+func RenderPrompt(settings Settings) ([]byte, error) {
+	// Parse the selected prompt version
+	tmpl, err := parsePrompt(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute the template with settings
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, settings); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // WritePromptFile generates and writes the prompt file to the specified output file
 func WritePromptFile(settings Settings) error {
-	// Get the directory from the output file path
-	outputDir := filepath.Dir(settings.OutputFile)
+	return writePrompt(settings, os.Stdout, os.Stderr)
+}
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// writePrompt renders the prompt and delivers it according to settings: printed for a
+// dry run, streamed to stdout when OutputFile is "-", otherwise written to disk.
+// Status messages go to stderr whenever stdout carries the prompt.
+func writePrompt(settings Settings, stdout, stderr io.Writer) error {
+	status := stdout
+	if settings.UsesStdout() {
+		status = stderr
+	}
+
+	mode, err := settings.OutputFileMode()
+	if err != nil {
+		return err
 	}
 
-	// Parse the template
-	tmpl, err := template.New("prompt").Parse(PromptTemplate)
+	// Refuse up front so neither file is written when one of them already exists
+	if settings.NoOverwrite && !settings.DryRun {
+		for _, path := range []string{settings.OutputFile, settings.ManifestPath()} {
+			if path == StdoutPath || path == "" {
+				continue
+			}
+			if _, err := os.Lstat(path); err == nil {
+				return fmt.Errorf("refusing to overwrite existing file: %s", path)
+			}
+		}
+	}
+
+	// Gather repository context; without a usable diff the prompt falls back to the generic guidance
+	if settings.Context == nil {
+		ctx, err := CollectContext(".", settings)
+		if err != nil {
+			fmt.Fprintf(status, "Warning: %v\n", err)
+		}
+		for _, warning := range ctx.Warnings {
+			fmt.Fprintf(status, "Warning: %s\n", warning)
+		}
+		settings.Context = ctx
+	}
+
+	// Estimate the prompt size, trimming the context when it exceeds max_tokens
+	tokenizer, err := LookupTokenizer(settings.Tokenizer)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
+	}
+	tokens, err := FitTokenBudget(settings, tokenizer)
+	if err != nil {
+		return err
+	}
+	if settings.Context != nil {
+		settings.Context.Tokens = tokens
 	}
 
-	// Create the output file
-	file, err := os.Create(settings.OutputFile)
+	// Render before touching the filesystem so a template error never leaves partial output
+	prompt, err := RenderPrompt(settings)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
-	defer file.Close()
+	printTokenReport(status, tokens)
 
-	// Execute the template with settings
-	if err := tmpl.Execute(file, settings); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	if settings.DryRun {
+		return printDryRun(stdout, settings, prompt)
+	}
+
+	if settings.OutputFile == StdoutPath {
+		if _, err := stdout.Write(prompt); err != nil {
+			return fmt.Errorf("failed to write prompt to stdout: %w", err)
+		}
+	} else {
+		if err := writeFileAtomic(settings.OutputFile, prompt, mode, !settings.NoOverwrite); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(status, "Successfully generated prompt file at: %s\n", settings.OutputFile)
+	}
+
+	manifestPath := settings.ManifestPath()
+	if manifestPath == "" {
+		return nil
+	}
+
+	// Describe the run for downstream steps; a missing diff only leaves the file list empty
+	if err := WriteManifest(settings, NewManifest(settings)); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully generated prompt file at: %s\n", settings.OutputFile)
+	fmt.Fprintf(status, "Successfully generated manifest file at: %s\n", manifestPath)
 	return nil
 }
 
+// printDryRun writes the resolved settings and rendered prompt without touching the filesystem
+func printDryRun(w io.Writer, settings Settings, prompt []byte) error {
+	resolved, err := json.MarshalIndent(redactSettings(settings), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	fmt.Fprintln(w, "Resolved settings:")
+	fmt.Fprintf(w, "%s\n\n", resolved)
+	fmt.Fprintln(w, "Rendered prompt:")
+	fmt.Fprintln(w, "======================")
+	if _, err := w.Write(prompt); err != nil {
+		return fmt.Errorf("failed to write prompt: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory, syncs it
+// and moves it into place, so readers only ever observe a complete file
+func writeFileAtomic(path string, data []byte, mode os.FileMode, overwrite bool) error {
+	dir := filepath.Dir(path)
+
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if overwrite {
+		if err := os.Rename(tmpPath, path); err != nil {
+			return fmt.Errorf("failed to move file into place: %w", err)
+		}
+	} else {
+		// Linking fails if the target exists, unlike rename which silently replaces it
+		if err := os.Link(tmpPath, path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("refusing to overwrite existing file: %s", path)
+			}
+			return fmt.Errorf("failed to move file into place: %w", err)
+		}
+	}
+
+	// Persist the directory entry; not every platform supports syncing directories
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -253,3 +253,240 @@ func TestWritePromptFileCreatesDirectory(t *testing.T) {
 		t.Error("Output file was not created")
 	}
 }
+
+func TestWritePromptFileWritesManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "abc123",
+		SourceSha:        "def456",
+		EnableBugs:       true,
+		CommentCount:     10,
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+		CustomRulesPath:  ".harness/rules/review.md",
+	}
+
+	if err := WritePromptFile(settings); err != nil {
+		t.Fatalf("WritePromptFile() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Manifest was not written next to the output file: %v", err)
+	}
+	output := string(content)
+	if !strings.Contains(output, `"review_output_file": "`+settings.ReviewOutputFile+`"`) {
+		t.Error("Manifest should contain the review output file path")
+	}
+	if !strings.Contains(output, `"template_hash": "sha256:`) {
+		t.Error("Manifest should contain the template hash")
+	}
+}
+
+func TestWritePromptFileModeAndOverwrite(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "abc123",
+		SourceSha:        "def456",
+		CommentCount:     10,
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+		FileMode:         "0600",
+		NoOverwrite:      true,
+	}
+
+	if err := WritePromptFile(settings); err != nil {
+		t.Fatalf("WritePromptFile() failed: %v", err)
+	}
+
+	info, err := os.Stat(settings.OutputFile)
+	if err != nil {
+		t.Fatalf("Output file was not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Output file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A second run must refuse to replace the existing prompt
+	if err := os.WriteFile(settings.OutputFile, []byte("keep me"), 0600); err != nil {
+		t.Fatalf("Failed to seed output file: %v", err)
+	}
+	if err := WritePromptFile(settings); err == nil {
+		t.Fatal("WritePromptFile() should refuse to overwrite an existing file")
+	}
+	content, err := os.ReadFile(settings.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(content) != "keep me" {
+		t.Errorf("Existing output file was modified: %q", content)
+	}
+
+	// Overwriting replaces the file in place
+	settings.NoOverwrite = false
+	if err := WritePromptFile(settings); err != nil {
+		t.Fatalf("WritePromptFile() with overwrite failed: %v", err)
+	}
+	content, err = os.ReadFile(settings.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(content), "test-repo") {
+		t.Error("Output file should have been replaced with the rendered prompt")
+	}
+}
+
+func TestWritePromptFileOverwritesByDefault(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+	}
+
+	// Settings built in code replace existing files, as before no_overwrite existed
+	for run := 1; run <= 2; run++ {
+		if err := WritePromptFile(settings); err != nil {
+			t.Fatalf("WritePromptFile() run %d failed: %v", run, err)
+		}
+	}
+}
+
+func TestWritePromptFileKeepsPullRequestWithoutDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "missing",
+		SourceSha:        "missing",
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+		PRTitle:          "Add login",
+	}
+
+	if err := WritePromptFile(settings); err != nil {
+		t.Fatalf("WritePromptFile() failed: %v", err)
+	}
+	content, err := os.ReadFile(settings.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(content), "Title: Add login\n") {
+		t.Error("The pull request title should be in the prompt when the diff is unavailable")
+	}
+}
+
+func TestWritePromptFileLeavesNoPartialOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+		FileMode:         "rw-r--r--",
+	}
+
+	if err := WritePromptFile(settings); err == nil {
+		t.Fatal("WritePromptFile() should fail for an invalid file mode")
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Output directory should be empty, found %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "nested", "task.txt")
+
+	if err := writeFileAtomic(path, []byte("first"), 0644, true); err != nil {
+		t.Fatalf("writeFileAtomic() failed: %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("second"), 0644, true); err != nil {
+		t.Fatalf("writeFileAtomic() overwrite failed: %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("third"), 0644, false); err == nil {
+		t.Error("writeFileAtomic() should refuse to overwrite when overwrite is false")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "second" {
+		t.Errorf("File content = %q, want %q", content, "second")
+	}
+
+	// Temporary files must never be left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Directory should only contain the target file, found %d entries", len(entries))
+	}
+}
+
+func TestWritePromptDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "abc123",
+		SourceSha:        "def456",
+		CommentCount:     10,
+		OutputFile:       filepath.Join(tempDir, "task.txt"),
+		ReviewOutputFile: filepath.Join(tempDir, "review.json"),
+		DryRun:           true,
+	}
+
+	var stdout, stderr strings.Builder
+	if err := writePrompt(settings, &stdout, &stderr); err != nil {
+		t.Fatalf("writePrompt() failed: %v", err)
+	}
+
+	output := stdout.String()
+	if !strings.Contains(output, `"repo_name": "test-repo"`) {
+		t.Error("Dry run should print the resolved settings")
+	}
+	if !strings.Contains(output, "abc123...def456") {
+		t.Error("Dry run should print the rendered prompt")
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Dry run should not write files, found %d entries", len(entries))
+	}
+}
+
+func TestWritePromptToStdout(t *testing.T) {
+	settings := Settings{
+		RepoName:         "test-repo",
+		MergeBaseSha:     "abc123",
+		SourceSha:        "def456",
+		CommentCount:     10,
+		OutputFile:       StdoutPath,
+		ReviewOutputFile: "../output/review.json",
+	}
+
+	var stdout, stderr strings.Builder
+	if err := writePrompt(settings, &stdout, &stderr); err != nil {
+		t.Fatalf("writePrompt() failed: %v", err)
+	}
+
+	if !strings.HasPrefix(stdout.String(), `assume the "test-repo" working directory`) {
+		t.Error("Stdout should contain only the rendered prompt")
+	}
+	if strings.Contains(stdout.String(), "Successfully generated") {
+		t.Error("Status messages must not be written to stdout")
+	}
+	if _, err := os.Stat("manifest.json"); err == nil {
+		t.Error("No manifest should be written when streaming to stdout")
+	}
+}